@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/policy"
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+// GetPolicyGuild gathers the guild state the access policy is evaluated against: its owner and roles. Both come
+// from the cache, which gateway events keep up to date; a guild which is not cached, such as during a dry run, is
+// fetched over REST instead.
+func GetPolicyGuild(b *Bot, guildID snowflake.ID) (policy.Guild, error) {
+	g := policy.Guild{ID: guildID}
+	if guild, ok := b.Client.Caches().Guild(guildID); ok {
+		g.OwnerID = guild.OwnerID
+		b.Client.Caches().RolesForEach(guildID, func(r discord.Role) {
+			g.Roles = append(g.Roles, r)
+		})
+		// the cache has no order; sort as Discord lists roles
+		slices.SortFunc(g.Roles, func(a, b discord.Role) int {
+			return cmp.Or(cmp.Compare(a.Position, b.Position), cmp.Compare(a.ID, b.ID))
+		})
+		return g, nil
+	}
+	guild, err := b.Client.Rest().GetGuild(guildID, false)
+	if err != nil {
+		return g, errors.WithMessage(err, "failed to get guild")
+	}
+	g.OwnerID = guild.OwnerID
+	g.Roles = guild.Roles
+	return g, nil
+}
+
+// GetAudience resolves who is responsible for tickets of the given category in the guild.
+func GetAudience(b *Bot, guildID snowflake.ID, category common.TicketCategory) (policy.Audience, error) {
+	g, err := GetPolicyGuild(b, guildID)
+	if err != nil {
+		return policy.Audience{}, err
+	}
+	return b.Cfg.GuildPolicy(guildID).Resolve(category.Access, g, categoryRoleIDs(b, guildID, category)...), nil
+}
+
+// categoryRoleIDs returns the roles responsible for the category on top of the access policy: the category's
+// ping roles and the guild's staff roles.
+func categoryRoleIDs(b *Bot, guildID snowflake.ID, category common.TicketCategory) []snowflake.ID {
+	return append(slices.Clone(category.PingRoles), GuildSettings(b, guildID).StaffRoleIDs...)
+}
+
+// GetTicketMentions returns the IDs of the roles and members to ping for the ticket, which depend on its priority:
+// urgent tickets ping everyone responsible for them, low priority tickets nobody, and any other ticket those first
+// in line for it. Nobody is pinged if the guild has turned pings off.
+func GetTicketMentions(b *Bot, t *store.Ticket) (roleIDs []string, userIDs []string, err error) {
+	if t.Priority == common.PriorityLow || !GuildSettings(b, t.GuildID).Pings() {
+		return nil, nil, nil
+	}
+	g, err := GetPolicyGuild(b, t.GuildID)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to resolve ticket audience")
+	}
+	category := GetTicketCategory(b, t)
+	p := b.Cfg.GuildPolicy(t.GuildID)
+	audience := p.Lead(category.Access, g, categoryRoleIDs(b, t.GuildID, category)...)
+	if t.Priority == common.PriorityUrgent {
+		audience = p.Resolve(category.Access, g, categoryRoleIDs(b, t.GuildID, category)...)
+	}
+	for _, id := range audience.RoleIDs() {
+		roleIDs = append(roleIDs, id.String())
+	}
+	for _, id := range audience.UserIDs {
+		userIDs = append(userIDs, id.String())
+	}
+	return slices.Compact(roleIDs), userIDs, nil
+}
+
+// AddTicketMembers adds the members responsible for the ticket who are not covered by a pinged role, such as the
+// guild owner, to the ticket thread. Failures are logged, as they can still be added by hand.
+func AddTicketMembers(b *Bot, t *store.Ticket) {
+	audience, err := GetAudience(b, t.GuildID, GetTicketCategory(b, t))
+	if err != nil {
+		slog.Error("Failed to resolve ticket audience", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+		return
+	}
+	for _, userID := range audience.UserIDs {
+		if err = b.Client.Rest().AddThreadMember(t.ThreadID, userID); err != nil {
+			slog.Warn(
+				"Failed to add member to ticket thread",
+				slog.Int64("ticket_id", t.ID),
+				slog.Any("user_id", userID),
+				slog.Any("err", err),
+			)
+		}
+	}
+}
+
+// CanManageTicket reports whether the member may manage the ticket, which is true for the ticket's opener
+// and for members of the ticket's support team.
+func CanManageTicket(b *Bot, t *store.Ticket, member *discord.ResolvedMember) (bool, error) {
+	if member == nil {
+		return false, nil
+	}
+	if member.User.ID == t.OpenerID {
+		return true, nil
+	}
+	return IsTicketStaff(b, t, member.Member)
+}
+
+// IsTicketStaff reports whether the access policy lets the member manage tickets of the ticket's category.
+func IsTicketStaff(b *Bot, t *store.Ticket, member discord.Member) (bool, error) {
+	g, err := GetPolicyGuild(b, t.GuildID)
+	if err != nil {
+		return false, err
+	}
+	category := GetTicketCategory(b, t)
+	return b.Cfg.GuildPolicy(t.GuildID).Allows(category.Access, g, member, categoryRoleIDs(b, t.GuildID, category)...), nil
+}
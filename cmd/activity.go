@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+// TrackTicketActivity records a message posted in a ticket thread against the stored ticket and adds it to the
+// ticket's searchable text. Messages outside ticket threads, from bots, system messages and messages in closed
+// tickets are ignored. Messages from the ticket's support team count as staff activity; everyone else, including the
+// opener, counts as user activity.
+func TrackTicketActivity(ctx context.Context, b *Bot, m discord.Message) error {
+	if m.Author.Bot || m.Author.System || m.GuildID == nil || !isConversationMessage(m.Type) {
+		return nil
+	}
+	t, err := b.Store.GetTicketByThread(ctx, m.ChannelID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return errors.WithMessage(err, "failed to get ticket")
+	}
+	if !t.IsOpen() {
+		return nil
+	}
+	var staff bool
+	if m.Author.ID != t.OpenerID && m.Member != nil {
+		member := *m.Member
+		member.User = m.Author
+		if staff, err = IsTicketStaff(b, t, member); err != nil {
+			return err
+		}
+	}
+	if err = b.Store.RecordTicketActivity(ctx, t.ID, m.CreatedAt, staff); err != nil {
+		return err
+	}
+	if m.Content == "" {
+		return nil
+	}
+	return b.Store.IndexTicketMessage(ctx, t.ID, m.Content)
+}
+
+func isConversationMessage(t discord.MessageType) bool {
+	return t == discord.MessageTypeDefault || t == discord.MessageTypeReply
+}
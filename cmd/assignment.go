@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+// ClaimConflictError is returned when claiming a ticket which is already assigned to someone else.
+type ClaimConflictError struct {
+	Number     int64
+	AssigneeID snowflake.ID
+}
+
+func (e ClaimConflictError) Error() string {
+	return fmt.Sprintf("ticket #%d is already assigned to %s", e.Number, e.AssigneeID)
+}
+
+// AssignTicket records the assignee of an open ticket, refreshes the ticket message and announces the change
+// in the ticket thread. Unless reassign is set, a ticket which is already assigned is refused with a
+// ClaimConflictError.
+func AssignTicket(
+	ctx context.Context, b *Bot, t *store.Ticket, actorID snowflake.ID, assignee discord.Member, reassign bool,
+) error {
+	if !t.IsOpen() {
+		return ErrTicketNotOpen
+	}
+	if t.AssigneeID != 0 && (!reassign || t.AssigneeID == assignee.User.ID) {
+		return ClaimConflictError{Number: t.Number, AssigneeID: t.AssigneeID}
+	}
+	ok, err := IsTicketStaff(b, t, assignee)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotStaff
+	}
+	previousID := t.AssigneeID
+	if err = b.Store.AssignTicket(ctx, t.ID, assignee.User.ID, previousID); err != nil {
+		return errors.WithMessage(err, "failed to assign ticket")
+	}
+	t.AssigneeID = assignee.User.ID
+	kind := store.TicketEventAssigned
+	if actorID == assignee.User.ID && previousID == 0 {
+		kind = store.TicketEventClaimed
+	}
+	var detail string
+	if previousID != 0 {
+		detail = "previously assigned to " + previousID.String()
+	}
+	RecordTicketEvent(ctx, b, store.TicketEvent{
+		TicketID: t.ID,
+		Kind:     kind,
+		ActorID:  actorID,
+		TargetID: assignee.User.ID,
+		Detail:   detail,
+	})
+	refreshTicket(b, t, assignee.EffectiveName())
+	announcement := fmt.Sprintf("<@%s> is now handling ticket #%d.", assignee.User.ID, t.Number)
+	if actorID != assignee.User.ID {
+		announcement = fmt.Sprintf("<@%s> assigned ticket #%d to <@%s>.", actorID, t.Number, assignee.User.ID)
+	}
+	if _, err = b.Client.Rest().CreateMessage(
+		t.ThreadID,
+		discord.NewMessageCreateBuilder().
+			SetContent(announcement).
+			SetAllowedMentions(&discord.AllowedMentions{Users: []snowflake.ID{assignee.User.ID}}).
+			Build(),
+	); err != nil {
+		return errors.WithMessage(err, "failed to announce ticket assignment")
+	}
+	slog.Info(
+		"Ticket assigned",
+		slog.Int64("ticket_id", t.ID),
+		slog.Int64("number", t.Number),
+		slog.Any("assignee_id", assignee.User.ID),
+		slog.Any("actor_id", actorID),
+	)
+	return nil
+}
+
+// UnassignTicket clears the assignee of an open ticket and refreshes the ticket message.
+func UnassignTicket(ctx context.Context, b *Bot, t *store.Ticket, actorID snowflake.ID) error {
+	if !t.IsOpen() {
+		return ErrTicketNotOpen
+	}
+	if t.AssigneeID == 0 {
+		return ErrTicketUnclaimed
+	}
+	previousID := t.AssigneeID
+	if err := b.Store.AssignTicket(ctx, t.ID, 0, previousID); err != nil {
+		return errors.WithMessage(err, "failed to unassign ticket")
+	}
+	t.AssigneeID = 0
+	RecordTicketEvent(ctx, b, store.TicketEvent{
+		TicketID: t.ID,
+		Kind:     store.TicketEventUnclaimed,
+		ActorID:  actorID,
+		TargetID: previousID,
+	})
+	refreshTicket(b, t, "")
+	if _, err := b.Client.Rest().CreateMessage(
+		t.ThreadID,
+		discord.NewMessageCreateBuilder().
+			SetContentf("<@%s> is no longer handling ticket #%d.", previousID, t.Number).
+			SetAllowedMentions(&discord.AllowedMentions{}).
+			Build(),
+	); err != nil {
+		return errors.WithMessage(err, "failed to announce ticket unassignment")
+	}
+	slog.Info(
+		"Ticket unassigned",
+		slog.Int64("ticket_id", t.ID),
+		slog.Int64("number", t.Number),
+		slog.Any("previous_assignee_id", previousID),
+		slog.Any("actor_id", actorID),
+	)
+	return nil
+}
+
+// refreshTicket re-renders the ticket message and, if enabled for the guild, renames the ticket thread.
+// Both are cosmetic, so failures are logged rather than returned.
+func refreshTicket(b *Bot, t *store.Ticket, assigneeName string) {
+	if err := UpdateTicketMessage(b, t); err != nil {
+		slog.Warn("Failed to update ticket message", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+	}
+	if !b.Cfg.Tickets.ShouldRenameOnClaim(t.GuildID) {
+		return
+	}
+	name := TicketThreadName(b, t, assigneeName)
+	if _, err := b.Client.Rest().UpdateChannel(t.ThreadID, discord.GuildThreadUpdate{Name: &name}); err != nil {
+		slog.Warn("Failed to rename ticket thread", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+	}
+}
+
+// UpdateTicketMessage re-renders the ticket content message and its controls from the stored ticket.
+func UpdateTicketMessage(b *Bot, t *store.Ticket) error {
+	if t.MessageID == 0 {
+		return nil
+	}
+	content, err := PopulateTicketContent(b, t)
+	if err != nil {
+		return errors.WithMessage(err, "failed to populate ticket content")
+	}
+	if _, err = b.Client.Rest().UpdateMessage(
+		t.ThreadID,
+		t.MessageID,
+		discord.NewMessageUpdateBuilder().
+			SetContent(content).
+			SetContainerComponents(TicketControls(t)...).
+			Build(),
+	); err != nil {
+		return errors.WithMessage(err, "failed to edit ticket message")
+	}
+	return nil
+}
@@ -17,25 +17,33 @@ import (
 	"github.com/disgoorg/snowflake/v2"
 
 	"github.com/kapparina/ticketsplease/cmd/commands"
+	"github.com/kapparina/ticketsplease/cmd/store"
 )
 
 func New(cfg Config, version, commit, tag string) *Bot {
-	return &Bot{
+	b := &Bot{
 		Cfg:       cfg,
 		Paginator: paginator.New(),
+		Drafts:    NewTicketDrafts(),
 		Version:   version,
 		Commit:    commit,
 		GitTag:    tag,
 	}
+	b.Reconciles = NewReconcileQueue(ReconcileDelay, b.reconcileGuild)
+	return b
 }
 
 type Bot struct {
 	Cfg       Config
 	Client    bot.Client
 	Paginator *paginator.Manager
-	Version   string
-	Commit    string
-	GitTag    string
+	Store     store.Store
+	Drafts    *TicketDrafts
+	// Reconciles holds the support channel reconciliations due after guild changes
+	Reconciles *ReconcileQueue
+	Version    string
+	Commit     string
+	GitTag     string
 }
 
 func (b *Bot) SetupBot(listeners ...bot.EventListener) error {
@@ -43,7 +51,7 @@ func (b *Bot) SetupBot(listeners ...bot.EventListener) error {
 	client, err := disgo.New(
 		os.Getenv("TICKETS_PLEASE_BOT_TOKEN"),
 		bot.WithGatewayConfigOpts(gateway.WithIntents(gateway.IntentsGuild, gateway.IntentGuildMessages, gateway.IntentMessageContent)),
-		bot.WithCacheConfigOpts(cache.WithCaches(cache.FlagGuilds)),
+		bot.WithCacheConfigOpts(cache.WithCaches(cache.FlagGuilds, cache.FlagChannels, cache.FlagRoles)),
 		bot.WithEventListeners(b.Paginator),
 		bot.WithEventListeners(listeners...),
 	)
@@ -54,6 +62,27 @@ func (b *Bot) SetupBot(listeners ...bot.EventListener) error {
 	return nil
 }
 
+// SetupStore opens the configured ticket store, applying any outstanding migrations, and caches guild settings in
+// memory. A read-only store is opened as it is and cannot be written to, such as for a dry run.
+func (b *Bot) SetupStore(ctx context.Context, readOnly bool) error {
+	slog.Info(
+		"Opening ticket store...",
+		slog.String("driver", b.Cfg.Database.Driver),
+		slog.String("path", b.Cfg.Database.Path),
+		slog.Bool("read_only", readOnly),
+	)
+	open := store.Open
+	if readOnly {
+		open = store.OpenReadOnly
+	}
+	s, err := open(ctx, b.Cfg.Database.Driver, b.Cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+	b.Store = store.WithSettingsCache(s)
+	return nil
+}
+
 func (b *Bot) OnReady(e *events.Ready) {
 	slog.Info("Setting presence...")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -6,5 +6,7 @@ var Commands = []discord.ApplicationCommandCreate{
 	test,
 	version,
 	Ticket,
+	TicketSettings,
+	Tickets,
 	Help,
 }
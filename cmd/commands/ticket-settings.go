@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/json"
+
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+var (
+	MaxSupportChannelNameLength  = 100
+	MaxSupportChannelTopicLength = 1024
+)
+
+// Ticket settings subcommands and subcommand groups
+const (
+	SettingsShow           = "show"
+	SettingsSupportChannel = "support-channel"
+	SettingsLogChannel     = "log-channel"
+	SettingsStaffRoles     = "staff-roles"
+	SettingsStaffRoleAdd   = "add"
+	SettingsStaffRoleDrop  = "remove"
+	SettingsCategory       = "category"
+	SettingsPings          = "pings"
+	SettingsLimits         = "limits"
+	SettingsReset          = "reset"
+	SettingsPreview        = "preview"
+)
+
+// TicketSettings lets guild admins manage their guild's ticket settings. It is hidden from members without the
+// Manage Server permission unless the guild grants it to others in its integration settings.
+var TicketSettings = discord.SlashCommandCreate{
+	Name:                     "ticket-settings",
+	Description:              "Manage this server's ticket settings",
+	DefaultMemberPermissions: json.NewNullablePtr(discord.PermissionManageGuild),
+	Contexts:                 []discord.InteractionContextType{discord.InteractionContextTypeGuild},
+	Options: []discord.ApplicationCommandOption{
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        SettingsShow,
+			Description: "Show this server's ticket settings",
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        SettingsSupportChannel,
+			Description: "Rename the support channel or change its topic",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "name",
+					Description: "The support channel's name; leave out to keep the current name",
+					Required:    false,
+					MaxLength:   &MaxSupportChannelNameLength,
+				},
+				discord.ApplicationCommandOptionString{
+					Name:        "topic",
+					Description: "The support channel's topic; leave out to keep the current topic",
+					Required:    false,
+					MaxLength:   &MaxSupportChannelTopicLength,
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        SettingsLogChannel,
+			Description: "Set the channel ticket transcripts are posted to",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionChannel{
+					Name:         "channel",
+					Description:  "The log channel; leave out to use the bot's default",
+					Required:     false,
+					ChannelTypes: []discord.ChannelType{discord.ChannelTypeGuildText},
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommandGroup{
+			Name:        SettingsStaffRoles,
+			Description: "Manage the roles treated as support team for every category",
+			Options: []discord.ApplicationCommandOptionSubCommand{
+				{
+					Name:        SettingsStaffRoleAdd,
+					Description: "Add a staff role",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionRole{
+							Name:        "role",
+							Description: "The role to add",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        SettingsStaffRoleDrop,
+					Description: "Remove a staff role",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionRole{
+							Name:        "role",
+							Description: "The role to remove",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        SettingsCategory,
+			Description: "Enable or disable a ticket category",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:         "category",
+					Description:  "The category",
+					Required:     true,
+					Autocomplete: true,
+				},
+				discord.ApplicationCommandOptionBool{
+					Name:        "enabled",
+					Description: "Whether members may open tickets in the category",
+					Required:    true,
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        SettingsPings,
+			Description: "Choose whom new and reopened tickets ping",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "mode",
+					Description: "Whom to ping",
+					Required:    true,
+					Choices: []discord.ApplicationCommandOptionChoiceString{
+						{Name: "The category's support team", Value: string(store.PingAll)},
+						{Name: "Nobody", Value: string(store.PingNone)},
+					},
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        SettingsLimits,
+			Description: "Limit how many tickets each member may have open",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionInt{
+					Name:        "max_open",
+					Description: "The most open tickets per member; 0 for no limit",
+					Required:    true,
+					MinValue:    json.Ptr(0),
+					MaxValue:    json.Ptr(100),
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        SettingsReset,
+			Description: "Reset all ticket settings to the bot's defaults",
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        SettingsPreview,
+			Description: "Show the changes the bot would make to this server's support channel and commands",
+		},
+	},
+}
@@ -1,57 +1,168 @@
 package commands
 
 import (
-	"log/slog"
-
 	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/json"
 
 	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/transcript"
 )
 
+// Default lengths of the ticket creation form's answers, used unless configured otherwise
 var (
-	MinTicketSubjectLength    = 3
-	MinTicketSubjectLengthPtr = &MinTicketSubjectLength
-	MaxTicketSubjectLength    = 100
-	MaxTicketSubjectLengthPtr = &MaxTicketSubjectLength
-	MaxTicketContentLength    = 1000
-	MaxTicketContentLengthPtr = &MaxTicketContentLength
+	MinTicketSubjectLength = 3
+	MaxTicketSubjectLength = 100
+	MinTicketContentLength = 3
+	MaxTicketContentLength = 1000
 )
 
+var (
+	MaxCloseReasonLength    = 500
+	MaxCloseReasonLengthPtr = &MaxCloseReasonLength
+	MaxPauseReasonLength    = 200
+	MaxPauseReasonLengthPtr = &MaxPauseReasonLength
+)
+
+// Ticket subcommands
+const (
+	TicketCreate     = "create"
+	TicketClose      = "close"
+	TicketReopen     = "reopen"
+	TicketAssign     = "assign"
+	TicketUnassign   = "unassign"
+	TicketTranscript = "transcript"
+	TicketPause      = "pause"
+	TicketResume     = "resume"
+	TicketSLA        = "sla"
+	TicketPriority   = "priority"
+)
+
+// TicketCreateCommandName is the full name users type to open a ticket
+var TicketCreateCommandName = Ticket.Name + " " + TicketCreate
+
 var Ticket = discord.SlashCommandCreate{
 	Name:        "ticket",
-	Description: "Create a ticket",
+	Description: "Create and manage tickets",
+	Contexts:    []discord.InteractionContextType{discord.InteractionContextTypeGuild},
 	Options: []discord.ApplicationCommandOption{
-		discord.ApplicationCommandOptionString{
-			Name:         "category",
-			Description:  "The category of the ticket",
-			Required:     true,
-			Autocomplete: false,
-			Choices: func() []discord.ApplicationCommandOptionChoiceString {
-				choices, err := common.GetCategoryChoices[discord.ApplicationCommandOptionChoiceString]()
-				if err != nil {
-					slog.Error("Failed to get category choices", slog.Any("err", err))
-				}
-				return choices
-			}(),
-		},
-		discord.ApplicationCommandOptionString{
-			Name:        "subject",
-			Description: "A brief description of the ticket",
-			Required:    true,
-			MinLength:   MinTicketSubjectLengthPtr,
-			MaxLength:   MaxTicketSubjectLengthPtr,
-		},
-		discord.ApplicationCommandOptionString{
-			Name:        "content",
-			Description: "Broader specifics of the ticket",
-			Required:    true,
-			MinLength:   MinTicketSubjectLengthPtr,
-			MaxLength:   MaxTicketContentLengthPtr,
-		},
-		discord.ApplicationCommandOptionAttachment{
-			Name:        "attachment",
-			Description: "An optional attachment to send with the ticket",
-			Required:    false,
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketCreate,
+			Description: "Create a ticket; the details are asked for in a form",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:         "category",
+					Description:  "The category of the ticket",
+					Required:     true,
+					Autocomplete: true,
+				},
+				discord.ApplicationCommandOptionAttachment{
+					Name:        "attachment",
+					Description: "An optional attachment to send with the ticket",
+					Required:    false,
+				},
+				discord.ApplicationCommandOptionInt{
+					Name:        "follow_up",
+					Description: "The number of a closed ticket this ticket follows up on",
+					Required:    false,
+					MinValue:    json.Ptr(1),
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketClose,
+			Description: "Close the ticket this thread belongs to",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "reason",
+					Description: "Why the ticket is being closed",
+					Required:    false,
+					MaxLength:   MaxCloseReasonLengthPtr,
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketReopen,
+			Description: "Reopen a closed ticket",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionInt{
+					Name:        "number",
+					Description: "The ticket number; defaults to the ticket this thread belongs to",
+					Required:    false,
+					MinValue:    json.Ptr(1),
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketAssign,
+			Description: "Assign the ticket this thread belongs to, replacing any current assignee",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionUser{
+					Name:        "member",
+					Description: "The support team member to handle the ticket",
+					Required:    true,
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketUnassign,
+			Description: "Remove the assignee of the ticket this thread belongs to",
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketTranscript,
+			Description: "Generate a transcript of the ticket this thread belongs to",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "format",
+					Description: "The transcript's format; defaults to PDF",
+					Required:    false,
+					Choices: []discord.ApplicationCommandOptionChoiceString{
+						{Name: "PDF", Value: string(transcript.FormatPDF)},
+						{Name: "HTML", Value: string(transcript.FormatHTML)},
+						{Name: "Markdown", Value: string(transcript.FormatMarkdown)},
+						{Name: "JSON", Value: string(transcript.FormatJSON)},
+					},
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketPause,
+			Description: "Pause inactivity reminders and automatic closure of the ticket this thread belongs to",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "reason",
+					Description: "Why the ticket is on hold, shown in the thread",
+					Required:    false,
+					MaxLength:   MaxPauseReasonLengthPtr,
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketResume,
+			Description: "Resume inactivity reminders and automatic closure of the ticket this thread belongs to",
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketSLA,
+			Description: "Show how the ticket this thread belongs to stands against its SLA deadlines",
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketPriority,
+			Description: "Set the priority of the ticket this thread belongs to",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "level",
+					Description: "The ticket's new priority",
+					Required:    true,
+					Choices:     priorityChoices(),
+				},
+			},
 		},
 	},
 }
+
+func priorityChoices() []discord.ApplicationCommandOptionChoiceString {
+	choices := make([]discord.ApplicationCommandOptionChoiceString, 0, len(common.Priorities))
+	for _, p := range common.Priorities {
+		choices = append(choices, discord.ApplicationCommandOptionChoiceString{Name: p.Label(), Value: string(p)})
+	}
+	return choices
+}
@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"github.com/disgoorg/disgo/discord"
+
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+// Tickets subcommands
+const (
+	TicketsList   = "list"
+	TicketsMine   = "mine"
+	TicketsSearch = "search"
+)
+
+// MaxSearchQueryLength bounds the text searched for by /tickets search
+var MaxSearchQueryLength = 100
+
+// TicketsStatusAll is the status choice listing tickets whatever their status
+const TicketsStatusAll = "all"
+
+// Tickets lists and searches tickets: all of them for the support team, or a member's own.
+var Tickets = discord.SlashCommandCreate{
+	Name:        "tickets",
+	Description: "List and search tickets",
+	Contexts:    []discord.InteractionContextType{discord.InteractionContextTypeGuild},
+	Options: []discord.ApplicationCommandOption{
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketsList,
+			Description: "List the tickets you support, most urgent first",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "status",
+					Description: "The status of the tickets; defaults to open",
+					Required:    false,
+					Choices: []discord.ApplicationCommandOptionChoiceString{
+						{Name: "Open", Value: string(store.TicketStatusOpen)},
+						{Name: "Closed", Value: string(store.TicketStatusClosed)},
+						{Name: "All", Value: TicketsStatusAll},
+					},
+				},
+				discord.ApplicationCommandOptionString{
+					Name:         "category",
+					Description:  "Only tickets of this category",
+					Required:     false,
+					Autocomplete: true,
+				},
+				discord.ApplicationCommandOptionUser{
+					Name:        "assignee",
+					Description: "Only tickets assigned to this member",
+					Required:    false,
+				},
+				discord.ApplicationCommandOptionUser{
+					Name:        "opener",
+					Description: "Only tickets opened by this member",
+					Required:    false,
+				},
+				discord.ApplicationCommandOptionString{
+					Name:        "priority",
+					Description: "Only tickets of this priority",
+					Required:    false,
+					Choices:     priorityChoices(),
+				},
+				discord.ApplicationCommandOptionInt{
+					Name:        "age",
+					Description: "Only tickets opened at least this long ago",
+					Required:    false,
+					Choices: []discord.ApplicationCommandOptionChoiceInt{
+						{Name: "1 hour", Value: 1},
+						{Name: "1 day", Value: 24},
+						{Name: "3 days", Value: 3 * 24},
+						{Name: "1 week", Value: 7 * 24},
+						{Name: "30 days", Value: 30 * 24},
+					},
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketsMine,
+			Description: "List your open and recently closed tickets",
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        TicketsSearch,
+			Description: "Search the tickets you support by their subject, content, category and messages",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:         "query",
+					Description:  "The words to look for",
+					Required:     true,
+					Autocomplete: true,
+					MaxLength:    &MaxSearchQueryLength,
+				},
+			},
+		},
+	},
+}
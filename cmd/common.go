@@ -3,34 +3,89 @@ package cmd
 import (
 	"context"
 	"log/slog"
+	"net/http"
+	"slices"
 	"time"
 
 	"github.com/disgoorg/disgo/discord"
-	"github.com/disgoorg/disgo/handler"
+	"github.com/disgoorg/disgo/rest"
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 
-	"github.com/kapparina/ticketsplease/cmd/commands"
 	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/policy"
 	"github.com/kapparina/ticketsplease/cmd/templates"
 )
 
-// Support channel constants
+// Default support channel settings, used unless a guild configures its own
 var (
 	SupportChannelName  = "support-tickets"
 	SupportChannelTopic = "Support tickets & suggestions"
 )
 
-// GetSupportChannel retrieves the ID of the support channel with the specified name in the given guild.
-// Returns the channel ID if found, otherwise returns 0 and an error if an issue occurs or the channel does not exist.
+// GetSupportChannel returns the ID of the guild's support channel: the channel the bot created or adopted for it,
+// as remembered in the guild's settings. It returns 0 if the guild has none, or if the remembered channel was deleted.
 func GetSupportChannel(b *Bot, guildID *snowflake.ID) (snowflake.ID, error) {
-	channels, err := b.Client.Rest().GetGuildChannels(*guildID)
+	channelID := GuildSettings(b, *guildID).SupportChannelID
+	if channelID == 0 {
+		return 0, nil
+	}
+	c, err := getGuildChannel(b, *guildID, channelID)
+	if err != nil {
+		return 0, err
+	}
+	if c == nil {
+		return 0, nil
+	}
+	return channelID, nil
+}
+
+// getGuildChannel returns the guild's channel from the cache, or over REST while the guild is not cached, such as
+// during a dry run. It returns nil if the guild has no such channel.
+func getGuildChannel(b *Bot, guildID snowflake.ID, channelID snowflake.ID) (discord.GuildChannel, error) {
+	if _, ok := b.Client.Caches().Guild(guildID); ok {
+		c, ok := b.Client.Caches().Channel(channelID)
+		if !ok || c.GuildID() != guildID {
+			return nil, nil
+		}
+		return c, nil
+	}
+	c, err := b.Client.Rest().GetChannel(channelID)
+	var restErr rest.Error
+	if errors.As(err, &restErr) && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WithMessage(err, "failed to get channel")
+	}
+	gc, ok := c.(discord.GuildChannel)
+	if !ok || gc.GuildID() != guildID {
+		return nil, nil
+	}
+	return gc, nil
+}
+
+// findLegacySupportChannel looks for the support channel of a guild set up before support channels were remembered:
+// a text channel with the support channel's name in which the bot has posted. Channels which merely share the name
+// are left alone.
+func findLegacySupportChannel(b *Bot, guildID snowflake.ID, name string) (snowflake.ID, error) {
+	channels, err := b.Client.Rest().GetGuildChannels(guildID)
 	if err != nil {
 		return 0, errors.WithMessage(err, "failed to get guild channels")
 	}
+	botID, err := botUserID(b)
+	if err != nil {
+		return 0, err
+	}
 	for _, c := range channels {
-		if c.Name() == SupportChannelName {
+		if c.Name() != name || c.Type() != discord.ChannelTypeGuildText {
+			continue
+		}
+		messages, err := b.Client.Rest().GetMessages(c.ID(), 0, 0, 0, 100)
+		if err != nil {
+			return 0, errors.WithMessage(err, "failed to get channel messages")
+		}
+		if slices.ContainsFunc(messages, func(m discord.Message) bool { return m.Author.ID == botID }) {
 			return c.ID(), nil
 		}
 	}
@@ -49,11 +104,7 @@ func ConfigureSupportChannel(ctx context.Context, b *Bot, guilds ...snowflake.ID
 				continue
 			}
 			eg.TryGo(func() error {
-				c, err := getOrCreateSupportChannel(b, &currentGuild)
-				if err != nil {
-					return err
-				}
-				if err = setupSupportChannel(b, &c, commands.Ticket.CommandName()); err != nil {
+				if err := ReconcileSupportChannel(b, currentGuild, 0); err != nil {
 					return err
 				}
 				slog.Info("Support channel setup successful", slog.Any("guild_id", currentGuild))
@@ -70,20 +121,24 @@ func ConfigureSupportChannel(ctx context.Context, b *Bot, guilds ...snowflake.ID
 	return nil
 }
 
+// Permissions the support channel's overwrites grant to staff roles and to the bot's own role.
+var (
+	staffOverwritePermissions = discord.PermissionsAllThread
+	botOverwritePermissions   = discord.PermissionsAllChannel | discord.PermissionsAllThread
+)
+
 // getSupportChannelOverrides generates a list of permission overrides for a support channel in a specific guild.
-// It filters roles based on the Moderation subset and assigns specified permissions to the generated overrides.
-func getSupportChannelOverrides(b *Bot, guildID snowflake.ID) []discord.PermissionOverwrite {
+// It grants thread permissions to every role the access policy puts in charge of moderator-level tickets or above.
+func getSupportChannelOverrides(b *Bot, g policy.Guild) []discord.PermissionOverwrite {
 	var overrides discord.PermissionOverwrites
-	roles, roleErr := b.Client.Rest().GetRoles(guildID)
-	if roleErr != nil {
-		return nil
-	}
-	filteredRoles := common.FilterRolesByPermission(roles, common.Moderation)
+	guildID := g.ID
+	roles := g.Roles
+	filteredRoles := b.Cfg.GuildPolicy(guildID).Resolve(common.AccessModerator, g).Roles
 	slog.Debug("Filtered roles", slog.Any("filtered_roles", filteredRoles))
 	for _, r := range filteredRoles {
 		o := discord.RolePermissionOverwrite{
 			RoleID: r.ID,
-			Allow:  discord.PermissionsAllThread,
+			Allow:  staffOverwritePermissions,
 			Deny:   discord.PermissionsNone,
 		}
 		overrides = append(overrides, o)
@@ -93,7 +148,7 @@ func getSupportChannelOverrides(b *Bot, guildID snowflake.ID) []discord.Permissi
 	for _, r := range filteredRoles {
 		o := discord.RolePermissionOverwrite{
 			RoleID: r.ID,
-			Allow:  discord.PermissionsAllChannel | discord.PermissionsAllThread,
+			Allow:  botOverwritePermissions,
 			Deny:   discord.PermissionsNone,
 		}
 		overrides = append(overrides, o)
@@ -103,8 +158,7 @@ func getSupportChannelOverrides(b *Bot, guildID snowflake.ID) []discord.Permissi
 		Allow: discord.PermissionSendMessagesInThreads |
 			discord.PermissionViewChannel |
 			discord.PermissionReadMessageHistory,
-		Deny: discord.PermissionReadMessageHistory |
-			discord.PermissionManageThreads |
+		Deny: discord.PermissionManageThreads |
 			discord.PermissionCreatePublicThreads |
 			discord.PermissionCreatePrivateThreads |
 			discord.PermissionSendMessages,
@@ -112,165 +166,20 @@ func getSupportChannelOverrides(b *Bot, guildID snowflake.ID) []discord.Permissi
 	return overrides
 }
 
-// getOrCreateSupportChannel finds or creates the support channel
-func getOrCreateSupportChannel(b *Bot, guildID *snowflake.ID) (snowflake.ID, error) {
-	c, err := GetSupportChannel(b, guildID)
-	if err != nil {
-		return 0, errors.WithMessage(err, "failed to get support channel")
-	}
-	if c != 0 {
-		return updateSupportChannel(b, c, guildID)
-	} else {
-		return createSupportChannel(b, guildID)
-	}
-}
-
-// createSupportChannel creates a new support channel
-func createSupportChannel(b *Bot, guildID *snowflake.ID) (snowflake.ID, error) {
-	supportChannelOverrides := getSupportChannelOverrides(b, *guildID)
-	c, err := b.Client.Rest().CreateGuildChannel(
-		*guildID,
-		discord.GuildTextChannelCreate{
-			Name:                 SupportChannelName,
-			Topic:                SupportChannelTopic,
-			PermissionOverwrites: supportChannelOverrides,
-		},
-	)
-	if err != nil {
-		return 0, errors.WithMessage(err, "failed to create support-tickets channel")
-	}
-	return c.ID(), nil
-}
-
-// updateSupportChannel updates an existing support channel
-func updateSupportChannel(b *Bot, channelID snowflake.ID, guildID *snowflake.ID) (snowflake.ID, error) {
-	supportChannelOverrides := getSupportChannelOverrides(b, *guildID)
-	targetChannelType := discord.ChannelTypeGuildText
-	c, err := b.Client.Rest().UpdateChannel(
-		channelID,
-		discord.GuildTextChannelUpdate{
-			Name:                 &SupportChannelName,
-			Type:                 &targetChannelType,
-			Topic:                &SupportChannelTopic,
-			PermissionOverwrites: &supportChannelOverrides,
-		},
-	)
-	if err != nil {
-		return 0, errors.WithMessage(err, "failed to update support-tickets channel")
-	}
-	return c.ID(), nil
-}
-
-// setupSupportChannel prepares a Discord support channel by clearing existing messages and posting a help message.
-func setupSupportChannel(b *Bot, c *snowflake.ID, cmdName string) error {
-	baseContent, err := templates.PopulateHelpData(templates.HelpData{CommandName: cmdName, Version: b.GitTag})
-	if err != nil {
-		return errors.WithMessage(err, "failed to populate base help message")
-	}
-	messages, err := getExistingBotMessages(b, c)
-	if err != nil {
-		return err
-	}
-	l := len(messages)
-	lastMessage := messages[l-1]
-	botUser, err := b.Client.Rest().GetCurrentUser("")
-	if err != nil {
-		return errors.WithMessage(err, "failed to get current user")
-	}
-	botUserId := botUser.ID
-	if messages[l-1].Content == baseContent {
-		slog.Info("Support channel is already configured")
-		return nil
-	} else if l >= 1 && lastMessage.Author.ID == botUserId {
-		slog.Info("Last message is from the bot, attempting to update...")
-		if _, err = b.Client.Rest().UpdateMessage(*c, lastMessage.ID, discord.NewMessageUpdateBuilder().
-			SetContent(baseContent).
-			Build(),
-		); err != nil {
-			slog.Error("Failed to update last message", slog.Any("err", err))
-			slog.Info("Attempting to overwrite existing messages...")
-			if err = deleteExistingMessages(b, c, messages); err != nil {
-				return err
-			}
-		} else {
-			return nil
-		}
-		slog.Info("Deletion successful, attempting to send new message...")
-		_, err = b.Client.Rest().CreateMessage(
-			*c,
-			discord.NewMessageCreateBuilder().
-				SetContent(baseContent).
-				Build(),
-		)
-		if err != nil {
-			return errors.WithMessage(err, "failed to send help message")
-		}
-	}
-	return nil
-}
-
 // PostHelpMessage sends a message with the given content to a specified Discord channel
 // using the provided bot instance.
-func PostHelpMessage(b *Bot, c *snowflake.ID, data templates.HelpData, e *handler.CommandEvent) error {
-	var content string
-	var err error
-	if e != nil {
-		content, err = templates.PopulateEphemeralHelpData(data)
-	} else {
-		content, err = templates.PopulateHelpData(data)
-	}
+func PostHelpMessage(b *Bot, c *snowflake.ID, data templates.HelpData) error {
+	content, err := templates.PopulateHelpData(data)
 	if err != nil {
 		return err
 	}
-	if e != nil {
-		err = e.CreateMessage(
-			discord.NewMessageCreateBuilder().
-				SetContent(content).
-				SetEphemeral(true).
-				Build(),
-		)
-	} else {
-		_, err = b.Client.Rest().CreateMessage(
-			*c,
-			discord.NewMessageCreateBuilder().
-				SetContent(content).
-				Build(),
-		)
-	}
-	if err != nil {
+	if _, err = b.Client.Rest().CreateMessage(
+		*c,
+		discord.NewMessageCreateBuilder().
+			SetContent(content).
+			Build(),
+	); err != nil {
 		return errors.WithMessage(err, "failed to create help message")
 	}
 	return nil
 }
-
-// deleteExistingMessages removes all existing bot messages from the specified Discord channel.
-// It retrieves messages using the bot client and deletes them in parallel with a concurrency limit.
-func deleteExistingMessages(b *Bot, c *snowflake.ID, messages []discord.Message) error {
-	deleteMessages := func(ctx context.Context, messages []discord.Message) error {
-		eg, _ := errgroup.WithContext(ctx)
-		eg.SetLimit(10)
-		for _, m := range messages {
-			currentMessage := m
-			eg.TryGo(func() error {
-				return b.Client.Rest().DeleteMessage(*c, currentMessage.ID)
-			})
-		}
-		return eg.Wait()
-	}
-	parentCtx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-	defer cancel()
-	if err := deleteMessages(parentCtx, messages); err != nil {
-		return errors.WithMessage(err, "failed to delete existing messages")
-	}
-	return nil
-}
-
-// getExistingBotMessages retrieves up to 100 messages from the specified Discord channel using the provided bot client.
-// Returns a slice of messages or an error if the retrieval fails.
-func getExistingBotMessages(b *Bot, c *snowflake.ID) ([]discord.Message, error) {
-	messages, err := b.Client.Rest().GetMessages(*c, 0, 0, 0, 100)
-	if err != nil {
-		return nil, errors.WithMessage(err, "failed to get existing support channel messages")
-	}
-	return messages, nil
-}
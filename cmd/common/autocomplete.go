@@ -11,7 +11,7 @@ type ChoiceOption interface {
 func GetFilteredAutocompleteOptions[T ChoiceOption](input string, options []T) []T {
 	var filteredChoices []T
 	for i, c := range options {
-		if strings.Contains(c.ChoiceName(), input) {
+		if strings.Contains(strings.ToLower(c.ChoiceName()), strings.ToLower(input)) {
 			filteredChoices = append(filteredChoices, options[i])
 		}
 	}
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// InactivityPolicy decides when a ticket waiting on its opener is nudged and eventually closed. Each threshold is
+// counted in hours since the support team's last message in the ticket; 0 skips that step.
+type InactivityPolicy struct {
+	// ReminderHours is when the opener is reminded that the support team awaits their reply
+	ReminderHours int `toml:"reminder_hours"`
+	// WarningHours is when the opener is warned that the ticket is about to be closed
+	WarningHours int `toml:"warning_hours"`
+	// CloseHours is when the ticket is closed as inactive
+	CloseHours int `toml:"close_hours"`
+}
+
+// Enabled reports whether the policy takes any step at all.
+func (p InactivityPolicy) Enabled() bool {
+	return p.ReminderHours > 0 || p.WarningHours > 0 || p.CloseHours > 0
+}
+
+// Thresholds returns the reminder, warning and closure thresholds in order; skipped steps are 0.
+func (p InactivityPolicy) Thresholds() [3]time.Duration {
+	return [3]time.Duration{
+		time.Duration(p.ReminderHours) * time.Hour,
+		time.Duration(p.WarningHours) * time.Hour,
+		time.Duration(p.CloseHours) * time.Hour,
+	}
+}
+
+// Validate checks that no threshold is negative and that the steps taken follow each other.
+func (p InactivityPolicy) Validate() error {
+	var last int
+	for _, hours := range []int{p.ReminderHours, p.WarningHours, p.CloseHours} {
+		if hours < 0 {
+			return errors.New("inactivity thresholds must not be negative")
+		}
+		if hours == 0 {
+			continue
+		}
+		if hours <= last {
+			return errors.New("inactivity thresholds must increase from reminder to warning to closure")
+		}
+		last = hours
+	}
+	return nil
+}
@@ -6,42 +6,6 @@ import (
 	"github.com/disgoorg/disgo/discord"
 )
 
-type PermissionSubset int
-
-const (
-	Moderation PermissionSubset = iota
-	Vanity
-	Administration
-)
-
-var PermissionAssignments = map[PermissionSubset][]discord.Permissions{
-	Moderation: {
-		discord.PermissionViewAuditLog,
-		discord.PermissionManageMessages,
-	},
-	Vanity: nil,
-	Administration: {
-		discord.PermissionAdministrator,
-	},
-}
-
-func FilterRolesByPermission(roles []discord.Role, targetSubset ...PermissionSubset) []discord.Role {
-	var filteredRoles []discord.Role
-	for _, r := range roles {
-		for _, subset := range targetSubset {
-			if r.Permissions.Has(PermissionAssignments[subset]...) {
-				slog.Debug(
-					"Filtered role",
-					slog.Any("role", r.Name),
-					slog.Any("method", "permission"),
-				)
-				filteredRoles = append(filteredRoles, r)
-			}
-		}
-	}
-	return filteredRoles
-}
-
 func FilterRolesByNames(roles []discord.Role, targetNames ...string) []discord.Role {
 	var filteredRoles []discord.Role
 	for _, r := range roles {
@@ -58,18 +22,3 @@ func FilterRolesByNames(roles []discord.Role, targetNames ...string) []discord.R
 	}
 	return filteredRoles
 }
-
-func FilterRolesRemoveManaged(roles []discord.Role) []discord.Role {
-	var filteredRoles []discord.Role
-	for _, r := range roles {
-		if !r.Managed {
-			slog.Debug(
-				"Filtered role",
-				slog.Any("role", r.Name),
-				slog.Any("method", "managed"),
-			)
-			filteredRoles = append(filteredRoles, r)
-		}
-	}
-	return filteredRoles
-}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"slices"
+	"strings"
+)
+
+// Priority is how urgently a ticket needs the support team's attention.
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// Priorities lists the priorities from the lowest to the highest.
+var Priorities = []Priority{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+// Valid reports whether the priority is one of Priorities.
+func (p Priority) Valid() bool {
+	return slices.Contains(Priorities, p)
+}
+
+// Rank orders the priorities from 0 for the lowest; unknown priorities rank as normal.
+func (p Priority) Rank() int {
+	if i := slices.Index(Priorities, p); i >= 0 {
+		return i
+	}
+	return slices.Index(Priorities, PriorityNormal)
+}
+
+// Label returns how the priority is shown to members.
+func (p Priority) Label() string {
+	if p == "" {
+		return ""
+	}
+	return strings.ToUpper(string(p[:1])) + string(p[1:])
+}
+
+// Marker returns the symbol put in front of the names of ticket threads with the priority, so that they stand
+// out in the thread list. Normal tickets have none.
+func (p Priority) Marker() string {
+	switch p {
+	case PriorityLow:
+		return "🔵"
+	case PriorityHigh:
+		return "🟠"
+	case PriorityUrgent:
+		return "🔴"
+	default:
+		return ""
+	}
+}
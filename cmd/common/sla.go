@@ -0,0 +1,97 @@
+package common
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SLATarget names a deadline of an SLA policy.
+type SLATarget string
+
+const (
+	// SLAFirstResponse is met once the support team first replies in the ticket
+	SLAFirstResponse SLATarget = "first_response"
+	// SLAResolution is met once the ticket is closed
+	SLAResolution SLATarget = "resolution"
+)
+
+// SLATargets lists the deadlines of an SLA policy in the order they fall due.
+var SLATargets = []SLATarget{SLAFirstResponse, SLAResolution}
+
+// Label returns how the target is shown to members.
+func (t SLATarget) Label() string {
+	switch t {
+	case SLAFirstResponse:
+		return "first response"
+	case SLAResolution:
+		return "resolution"
+	default:
+		return string(t)
+	}
+}
+
+// SLAPolicy sets the deadlines tickets are expected to meet, counted from the ticket's creation; 0 sets no
+// deadline.
+type SLAPolicy struct {
+	// FirstResponseMinutes is how long the support team has to first reply in the ticket
+	FirstResponseMinutes int `toml:"first_response_minutes"`
+	// ResolutionMinutes is how long the support team has to close the ticket
+	ResolutionMinutes int `toml:"resolution_minutes"`
+	// WarnPercent is how much of a deadline may pass before the ticket is escalated as nearing it; 0 only escalates
+	// tickets which breach their deadline
+	WarnPercent int `toml:"warn_percent"`
+	// PriorityPercent scales the deadlines of tickets by their priority, e.g. 50 halves them; priorities not given
+	// keep the deadlines as they are
+	PriorityPercent map[Priority]int `toml:"priority_percent"`
+}
+
+// Enabled reports whether the policy sets any deadline.
+func (p SLAPolicy) Enabled() bool {
+	return p.FirstResponseMinutes > 0 || p.ResolutionMinutes > 0
+}
+
+// Budget returns the time allowed for the target in tickets of the given priority, or 0 if the policy sets no
+// deadline for it.
+func (p SLAPolicy) Budget(target SLATarget, priority Priority) time.Duration {
+	var minutes int
+	switch target {
+	case SLAFirstResponse:
+		minutes = p.FirstResponseMinutes
+	case SLAResolution:
+		minutes = p.ResolutionMinutes
+	}
+	budget := time.Duration(minutes) * time.Minute
+	if percent, ok := p.PriorityPercent[priority]; ok {
+		budget = budget * time.Duration(percent) / 100
+	}
+	return budget
+}
+
+// WarnBudget returns how much of the target's budget may pass in tickets of the given priority before the ticket
+// is escalated as nearing its deadline, or 0 if it is not escalated early.
+func (p SLAPolicy) WarnBudget(target SLATarget, priority Priority) time.Duration {
+	if p.WarnPercent <= 0 || p.WarnPercent >= 100 {
+		return 0
+	}
+	return p.Budget(target, priority) * time.Duration(p.WarnPercent) / 100
+}
+
+// Validate checks that no deadline is negative and that the warning falls before the deadline.
+func (p SLAPolicy) Validate() error {
+	if p.FirstResponseMinutes < 0 || p.ResolutionMinutes < 0 {
+		return errors.New("SLA deadlines must not be negative")
+	}
+	if p.WarnPercent < 0 || p.WarnPercent >= 100 {
+		return errors.New("SLA warn_percent must be between 0 and 99")
+	}
+	for priority, percent := range p.PriorityPercent {
+		if !priority.Valid() {
+			return errors.Errorf("SLA priority_percent: unknown priority %q, expected one of %v", priority, Priorities)
+		}
+		if percent <= 0 {
+			return errors.Errorf("SLA priority_percent for %s must be positive", priority)
+		}
+	}
+	return nil
+}
@@ -2,120 +2,290 @@ package common
 
 import (
 	"reflect"
+	"regexp"
+	"slices"
+	"strings"
 
+	"github.com/disgoorg/snowflake/v2"
 	"github.com/pkg/errors"
 )
 
+// AccessLevel names the tier of staff responsible for a category of tickets.
+type AccessLevel string
+
+//goland:noinspection GoCommentStart
+const (
+	AccessModerator AccessLevel = "moderator"
+	AccessStaff     AccessLevel = "staff"
+	AccessAdmin     AccessLevel = "admin"
+	AccessOwner     AccessLevel = "owner"
+)
+
+// AccessLevels lists the access levels from the lowest to the highest tier.
+var AccessLevels = []AccessLevel{AccessModerator, AccessStaff, AccessAdmin, AccessOwner}
+
+// Valid reports whether the access level is one of AccessLevels.
+func (a AccessLevel) Valid() bool {
+	for _, l := range AccessLevels {
+		if a == l {
+			return true
+		}
+	}
+	return false
+}
+
+// maxCategoryLabelLength is Discord's limit on the length of a command option choice's name.
+const maxCategoryLabelLength = 100
+
+var categoryTitlePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// TicketCategory describes a kind of ticket users may open. Title identifies the category and is what gets
+// stored with each ticket; Description is what users see. In per-guild overrides, only the fields which are
+// set replace those of the category with the same title.
 type TicketCategory struct {
-	Title       string
-	Description string
+	Title       string         `toml:"title"`
+	Description string         `toml:"description"`
+	Emoji       string         `toml:"emoji"`
+	Access      AccessLevel    `toml:"access"`
+	PingRoles   []snowflake.ID `toml:"ping_roles"`
+	Enabled     *bool          `toml:"enabled"`
+	// Fields are extra questions asked in the ticket creation form, after the subject and description
+	Fields []TicketField `toml:"fields"`
+	// Inactivity replaces the guild's inactivity policy for tickets in the category
+	Inactivity *InactivityPolicy `toml:"inactivity"`
+	// SLA replaces the guild's SLA policy for tickets in the category
+	SLA *SLAPolicy `toml:"sla"`
 }
 
-type Category int
+// Discord limits on modal text inputs; a modal holds at most five inputs, two of which are the subject and
+// description.
+const (
+	MaxCategoryFields     = 3
+	maxFieldLabelLength   = 45
+	maxFieldPlaceholder   = 100
+	maxFieldLength        = 4000
+	DefaultFieldMaxLength = 200
+)
 
-func (c Category) RequiresMod() bool {
-	return c < CategoryStaffSupport
+// TicketField is an extra question asked when a ticket is created in a category, such as "Steps to reproduce".
+type TicketField struct {
+	// ID identifies the answer in the submitted form and must be unique within the category
+	ID          string `toml:"id"`
+	Label       string `toml:"label"`
+	Placeholder string `toml:"placeholder"`
+	// Paragraph asks for a multi-line answer instead of a single line
+	Paragraph bool `toml:"paragraph"`
+	Required  bool `toml:"required"`
+	MinLength int  `toml:"min_length"`
+	// MaxLength defaults to DefaultFieldMaxLength
+	MaxLength int `toml:"max_length"`
 }
 
-func (c Category) RequiresStaff() bool {
-	return c < CategoryAdminSupport
+// Limit returns the most characters an answer to the field may have.
+func (f TicketField) Limit() int {
+	if f.MaxLength > 0 {
+		return f.MaxLength
+	}
+	return DefaultFieldMaxLength
 }
 
-func (c Category) RequiresAdmin() bool {
-	return c < CategoryOwnerSuggestion
+// FormLength returns the most characters the category's extra fields can add to a ticket.
+func (c TicketCategory) FormLength() int {
+	var n int
+	for _, f := range c.Fields {
+		n += f.Limit()
+	}
+	return n
 }
 
-func (c Category) RequiresOwner() bool {
-	return c >= CategoryAdminSupport
+// IsEnabled reports whether users may open new tickets in the category. Categories are enabled unless disabled.
+func (c TicketCategory) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
 }
 
-//goland:noinspection GoCommentStart
-const (
-	CategoryGeneralSuggestion Category = iota
-	CategoryGeneralSupport
-	CategoryUserSupport
-	CategoryUserSuggestion
-	CategoryStaffSuggestion
-	CategoryStaffSupport
-	CategoryModSuggestion
-	CategoryModSupport
-	CategoryAdminSuggestion
-	CategoryAdminSupport
-	CategoryOwnerSuggestion
-	CategoryOwnerSupport
-)
+// Label returns the description of the category, prefixed with its emoji if that is a plain unicode emoji.
+func (c TicketCategory) Label() string {
+	if c.Emoji == "" || strings.HasPrefix(c.Emoji, "<") {
+		return c.Description
+	}
+	return c.Emoji + " " + c.Description
+}
 
-var Categories = map[Category]TicketCategory{
-	CategoryGeneralSupport: {
-		Title:       "general-support",
-		Description: "General support questions",
-	},
-	CategoryModSupport: {
-		Title:       "mod-support",
-		Description: "Moderation support questions",
-	},
-	CategoryStaffSupport: {
-		Title:       "staff-support",
-		Description: "Mod/Admin support questions",
-	},
-	CategoryAdminSupport: {
-		Title:       "admin-support",
-		Description: "Admin support questions",
-	},
-	CategoryOwnerSupport: {
-		Title:       "owner-support",
-		Description: "Owner support questions",
-	},
-	CategoryUserSupport: {
-		Title:       "user-support",
-		Description: "User support questions",
-	},
-	CategoryGeneralSuggestion: {
-		Title:       "general-suggestion",
-		Description: "General suggestion",
-	},
-	CategoryModSuggestion: {
-		Title:       "mod-suggestion",
-		Description: "Mod suggestion",
-	},
-	CategoryStaffSuggestion: {
-		Title:       "staff-suggestion",
-		Description: "Mod/Admin suggestion",
-	},
-	CategoryAdminSuggestion: {
-		Title:       "admin-suggestion",
-		Description: "Admin suggestion",
-	},
-	CategoryOwnerSuggestion: {
-		Title:       "owner-suggestion",
-		Description: "Owner suggestion",
-	},
-	CategoryUserSuggestion: {
-		Title:       "user-suggestion",
-		Description: "User suggestion",
-	},
+// merge returns the category with the fields set in override applied on top.
+func (c TicketCategory) merge(override TicketCategory) TicketCategory {
+	if override.Description != "" {
+		c.Description = override.Description
+	}
+	if override.Emoji != "" {
+		c.Emoji = override.Emoji
+	}
+	if override.Access != "" {
+		c.Access = override.Access
+	}
+	if override.PingRoles != nil {
+		c.PingRoles = override.PingRoles
+	}
+	if override.Enabled != nil {
+		c.Enabled = override.Enabled
+	}
+	if override.Fields != nil {
+		c.Fields = override.Fields
+	}
+	if override.Inactivity != nil {
+		c.Inactivity = override.Inactivity
+	}
+	if override.SLA != nil {
+		c.SLA = override.SLA
+	}
+	return c
 }
 
-func FindCategoryByDescription(description string) (Category, bool) {
-	for k, v := range Categories {
-		if v.Description == description {
-			return k, true
+// Categories is an ordered list of ticket categories.
+type Categories []TicketCategory
+
+// DefaultCategories are used when the configuration does not define any categories. With the default access policy,
+// whose staff tier is empty, they ping the same roles as the built-in categories always have: moderators and admins
+// for the general, user and staff suggestion categories, and admins for the rest. The owner categories are the
+// exception, going to the owner tier and the guild owner rather than to admins.
+var DefaultCategories = Categories{
+	{Title: "general-support", Description: "General support questions", Access: AccessModerator},
+	{Title: "general-suggestion", Description: "General suggestion", Access: AccessModerator},
+	{Title: "user-support", Description: "User support questions", Access: AccessModerator},
+	{Title: "user-suggestion", Description: "User suggestion", Access: AccessModerator},
+	{Title: "staff-support", Description: "Mod/Admin support questions", Access: AccessStaff},
+	{Title: "staff-suggestion", Description: "Mod/Admin suggestion", Access: AccessModerator},
+	{Title: "mod-support", Description: "Moderation support questions", Access: AccessStaff},
+	{Title: "mod-suggestion", Description: "Mod suggestion", Access: AccessStaff},
+	{Title: "admin-support", Description: "Admin support questions", Access: AccessAdmin},
+	{Title: "admin-suggestion", Description: "Admin suggestion", Access: AccessStaff},
+	{Title: "owner-support", Description: "Owner support questions", Access: AccessOwner},
+	{Title: "owner-suggestion", Description: "Owner suggestion", Access: AccessOwner},
+}
+
+// Find returns the category with the given title.
+func (c Categories) Find(title string) (TicketCategory, bool) {
+	for _, category := range c {
+		if category.Title == title {
+			return category, true
+		}
+	}
+	return TicketCategory{}, false
+}
+
+// Enabled returns the categories users may open new tickets in.
+func (c Categories) Enabled() Categories {
+	var enabled Categories
+	for _, category := range c {
+		if category.IsEnabled() {
+			enabled = append(enabled, category)
+		}
+	}
+	return enabled
+}
+
+// Merge applies per-guild overrides to the categories. Overrides whose title matches an existing category
+// replace the fields they set; any other override is appended as a new category.
+func (c Categories) Merge(overrides Categories) Categories {
+	merged := make(Categories, len(c), len(c)+len(overrides))
+	copy(merged, c)
+	for _, override := range overrides {
+		i := slices.IndexFunc(merged, func(c TicketCategory) bool { return c.Title == override.Title })
+		if i < 0 {
+			merged = append(merged, override)
+			continue
+		}
+		merged[i] = merged[i].merge(override)
+	}
+	return merged
+}
+
+// Validate checks that every category has a unique, well-formed title, a description and a known access level.
+func (c Categories) Validate() error {
+	seen := make(map[string]bool, len(c))
+	for _, category := range c {
+		if !categoryTitlePattern.MatchString(category.Title) {
+			return errors.Errorf(
+				"category title %q must be 1-50 lowercase letters, digits, dashes or underscores", category.Title,
+			)
+		}
+		if seen[category.Title] {
+			return errors.Errorf("category %q is defined more than once", category.Title)
+		}
+		seen[category.Title] = true
+		if category.Description == "" {
+			return errors.Errorf("category %q has no description", category.Title)
+		}
+		if len([]rune(category.Label())) > maxCategoryLabelLength {
+			return errors.Errorf(
+				"category %q: emoji and description must not exceed %d characters", category.Title, maxCategoryLabelLength,
+			)
+		}
+		if !category.Access.Valid() {
+			return errors.Errorf(
+				"category %q has unknown access level %q, expected one of %v", category.Title, category.Access, AccessLevels,
+			)
+		}
+		if err := validateFields(category.Fields); err != nil {
+			return errors.WithMessagef(err, "category %q", category.Title)
+		}
+		if category.Inactivity != nil {
+			if err := category.Inactivity.Validate(); err != nil {
+				return errors.WithMessagef(err, "category %q", category.Title)
+			}
+		}
+		if category.SLA != nil {
+			if err := category.SLA.Validate(); err != nil {
+				return errors.WithMessagef(err, "category %q", category.Title)
+			}
+		}
+	}
+	return nil
+}
+
+// reservedFieldIDs are used by the ticket creation form itself.
+var reservedFieldIDs = []string{"subject", "content"}
+
+func validateFields(fields []TicketField) error {
+	if len(fields) > MaxCategoryFields {
+		return errors.Errorf("has %d fields, at most %d are allowed", len(fields), MaxCategoryFields)
+	}
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if !categoryTitlePattern.MatchString(f.ID) || slices.Contains(reservedFieldIDs, f.ID) {
+			return errors.Errorf("field id %q must be 1-50 lowercase letters, digits, dashes or underscores "+
+				"and not one of %v", f.ID, reservedFieldIDs)
+		}
+		if seen[f.ID] {
+			return errors.Errorf("field %q is defined more than once", f.ID)
+		}
+		seen[f.ID] = true
+		if f.Label == "" || len([]rune(f.Label)) > maxFieldLabelLength {
+			return errors.Errorf("field %q must have a label of 1-%d characters", f.ID, maxFieldLabelLength)
+		}
+		if len([]rune(f.Placeholder)) > maxFieldPlaceholder {
+			return errors.Errorf("field %q: placeholder must not exceed %d characters", f.ID, maxFieldPlaceholder)
+		}
+		if f.MinLength < 0 || f.Limit() > maxFieldLength || f.MinLength > f.Limit() {
+			return errors.Errorf("field %q: lengths must satisfy 0 <= min_length <= max_length <= %d", f.ID, maxFieldLength)
 		}
 	}
-	return -1, false
+	return nil
 }
 
-func GetCategoryChoices[T ChoiceOption]() ([]T, error) {
+// GetCategoryChoices builds a choice for each enabled category, named after the category's label and
+// valued with its title.
+func GetCategoryChoices[T ChoiceOption](categories Categories) ([]T, error) {
 	typeOfT := reflect.TypeFor[T]()
-	choices := make([]T, 0, len(Categories))
+	enabled := categories.Enabled()
+	choices := make([]T, 0, len(enabled))
 	valueType, _ := typeOfT.FieldByName("Value")
 	if valueType.Type.Kind() != reflect.String {
 		return nil, errors.New("value type must be string")
 	}
-	for _, info := range Categories {
+	for _, info := range enabled {
 		choice := reflect.New(typeOfT).Elem()
-		choice.FieldByName("Name").SetString(info.Title)
-		choice.FieldByName("Value").SetString(info.Description)
+		choice.FieldByName("Name").SetString(info.Label())
+		choice.FieldByName("Value").SetString(info.Title)
 		choices = append(choices, choice.Interface().(T))
 	}
 	return choices, nil
@@ -0,0 +1,44 @@
+package components
+
+import (
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+	"github.com/kapparina/ticketsplease/cmd/commands"
+	"github.com/kapparina/ticketsplease/cmd/templates"
+)
+
+// PanelCategoryComponent starts the ticket flow for the category picked from the ticket panel's menu by showing
+// the ticket creation form, just like the ticket creation command does.
+func PanelCategoryComponent(b *cmd.Bot) handler.ComponentHandler {
+	return func(e *handler.ComponentEvent) error {
+		var title string
+		if values := e.StringSelectMenuInteractionData().Values; len(values) > 0 {
+			title = values[0]
+		}
+		category, err := cmd.ValidateNewTicket(e.Ctx, b, *e.GuildID(), e.User().ID, title, 0)
+		if msg, ok := cmd.TicketErrorMessage(err); ok {
+			return e.CreateMessage(discord.NewMessageCreateBuilder().SetContent(msg).SetEphemeral(true).Build())
+		} else if err != nil {
+			return err
+		}
+		// forget any attachment left behind by an abandoned ticket creation command
+		b.Drafts.Put(*e.GuildID(), e.User().ID, "")
+		return e.Modal(cmd.TicketFormModal(b, category, 0))
+	}
+}
+
+// PanelHelpComponent explains how tickets work to the member who pressed the ticket panel's help button.
+func PanelHelpComponent(b *cmd.Bot) handler.ComponentHandler {
+	return func(e *handler.ComponentEvent) error {
+		content, err := templates.PopulateEphemeralHelpData(templates.HelpData{
+			CommandName: commands.TicketCreateCommandName,
+			Version:     b.GitTag,
+		})
+		if err != nil {
+			return err
+		}
+		return e.CreateMessage(discord.NewMessageCreateBuilder().SetContent(content).SetEphemeral(true).Build())
+	}
+}
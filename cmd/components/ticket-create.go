@@ -0,0 +1,41 @@
+package components
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+// CreateTicketModal opens a ticket from the submitted ticket creation form. The checks made before the form was
+// shown are repeated, as the guild's settings or the member's open tickets may have changed in the meantime.
+func CreateTicketModal(b *cmd.Bot) handler.ModalHandler {
+	return func(e *handler.ModalEvent) error {
+		guildID := *e.GuildID()
+		followUpOf := cmd.ParseTicketFormFollowUp(e.Vars["follow_up"])
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			category, err := cmd.ValidateNewTicket(ctx, b, guildID, e.User().ID, e.Vars["category"], followUpOf)
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			t := store.Ticket{
+				GuildID:       guildID,
+				OpenerID:      e.User().ID,
+				OpenerName:    e.User().Username,
+				Category:      category.Title,
+				Subject:       e.Data.Text("subject"),
+				Content:       e.Data.Text("content"),
+				Answers:       cmd.TicketAnswers(category, e.Data),
+				AttachmentURL: b.Drafts.Take(guildID, e.User().ID),
+				FollowUpOf:    followUpOf,
+			}
+			if err = cmd.OpenTicket(ctx, b, &t); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Created ticket #%d: <#%s>", t.Number, t.ThreadID), nil
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package components
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+	"github.com/kapparina/ticketsplease/cmd/commands"
+	"github.com/kapparina/ticketsplease/cmd/common"
+)
+
+// CloseTicketComponent asks for a closure reason once the member has been confirmed to be allowed to close the ticket.
+func CloseTicketComponent(b *cmd.Bot) handler.ComponentHandler {
+	return func(e *handler.ComponentEvent) error {
+		t, err := cmd.GetManageableTicket(e.Ctx, b, e.Channel().ID(), e.Member())
+		if err == nil && !t.IsOpen() {
+			err = cmd.ErrTicketNotOpen
+		}
+		if msg, ok := cmd.TicketErrorMessage(err); ok {
+			return e.CreateMessage(discord.NewMessageCreateBuilder().SetContent(msg).SetEphemeral(true).Build())
+		} else if err != nil {
+			return err
+		}
+		return e.Modal(discord.NewModalCreateBuilder().
+			SetCustomID(cmd.CloseTicketID).
+			SetTitle("Close ticket").
+			AddActionRow(
+				discord.NewParagraphTextInput("reason", "Reason").
+					WithRequired(false).
+					WithMaxLength(commands.MaxCloseReasonLength).
+					WithPlaceholder(cmd.DefaultCloseReason),
+			).
+			Build(),
+		)
+	}
+}
+
+// CloseTicketModal closes the ticket with the reason submitted through the close modal.
+func CloseTicketModal(b *cmd.Bot) handler.ModalHandler {
+	return func(e *handler.ModalEvent) error {
+		reason := e.Data.Text("reason")
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetManageableTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.CloseTicket(ctx, b, t, e.User().ID, reason); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Closed ticket #%d.", t.Number), nil
+		})
+	}
+}
+
+// ReopenTicketComponent reopens the ticket whose closure summary the button was attached to.
+func ReopenTicketComponent(b *cmd.Bot) handler.ComponentHandler {
+	return func(e *handler.ComponentEvent) error {
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetManageableTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.ReopenTicket(ctx, b, t, e.User().ID); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Reopened ticket #%d.", t.Number), nil
+		})
+	}
+}
+
+// ClaimTicketComponent assigns the ticket to the support team member who pressed the button,
+// refusing if someone else has already claimed it.
+func ClaimTicketComponent(b *cmd.Bot) handler.ComponentHandler {
+	return func(e *handler.ComponentEvent) error {
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetStaffTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.AssignTicket(ctx, b, t, e.User().ID, e.Member().Member, false); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("You have claimed ticket #%d.", t.Number), nil
+		})
+	}
+}
+
+// UnclaimTicketComponent removes the assignee of the ticket.
+func UnclaimTicketComponent(b *cmd.Bot) handler.ComponentHandler {
+	return func(e *handler.ComponentEvent) error {
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetStaffTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.UnassignTicket(ctx, b, t, e.User().ID); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Ticket #%d is no longer assigned.", t.Number), nil
+		})
+	}
+}
+
+// PriorityTicketComponent sets the priority of the ticket to the one picked from the priority menu.
+func PriorityTicketComponent(b *cmd.Bot) handler.ComponentHandler {
+	return func(e *handler.ComponentEvent) error {
+		priority := common.Priority(e.StringSelectMenuInteractionData().Values[0])
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetStaffTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.SetTicketPriority(ctx, b, t, e.User().ID, priority); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Ticket #%d is now %s priority.", t.Number, priority), nil
+		})
+	}
+}
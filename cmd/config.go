@@ -4,9 +4,17 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/pelletier/go-toml/v2"
+
+	"github.com/kapparina/ticketsplease/cmd/commands"
+	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/hours"
+	"github.com/kapparina/ticketsplease/cmd/policy"
+	"github.com/kapparina/ticketsplease/cmd/store"
+	"github.com/kapparina/ticketsplease/cmd/transcript"
 )
 
 func LoadConfig(path string) (*Config, error) {
@@ -15,16 +23,118 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to open config: %w", err)
 	}
 
-	var cfg Config
+	cfg := Config{
+		Database: DatabaseConfig{
+			Driver: store.DriverSQLite,
+			Path:   "tickets.db",
+		},
+		Tickets: TicketsConfig{
+			ReopenWindowDays: 7,
+			MinSubjectLength: commands.MinTicketSubjectLength,
+			MaxSubjectLength: commands.MaxTicketSubjectLength,
+			MinContentLength: commands.MinTicketContentLength,
+			MaxContentLength: commands.MaxTicketContentLength,
+		},
+		Transcripts: TranscriptsConfig{
+			Formats:   []string{"html", "markdown", "json"},
+			Directory: "transcripts",
+		},
+		Access: policy.Default,
+	}
 	if err = toml.NewDecoder(file).Decode(&cfg); err != nil {
 		return nil, err
 	}
+	for _, format := range cfg.Transcripts.Formats {
+		if _, err = transcript.ParseFormat(format); err != nil {
+			return nil, fmt.Errorf("invalid transcript format: %w", err)
+		}
+	}
+	if cfg.Transcripts.PDFFont != "" {
+		font, err := transcript.LoadFont(cfg.Transcripts.PDFFont)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transcript font: %w", err)
+		}
+		cfg.Transcripts.font = &font
+	}
+	if err = cfg.Access.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid access policy: %w", err)
+	}
+	if err = cfg.Tickets.Inactivity.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid inactivity policy: %w", err)
+	}
+	if err = cfg.Tickets.SLA.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid SLA policy: %w", err)
+	}
+	if len(cfg.Categories) == 0 {
+		cfg.Categories = common.DefaultCategories
+	}
+	if err = cfg.Tickets.validateForm(cfg.Categories); err != nil {
+		return nil, fmt.Errorf("invalid ticket form: %w", err)
+	}
+	if err = cfg.Categories.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid categories: %w", err)
+	}
+	for guildID, g := range cfg.Tickets.Guilds {
+		categories := cfg.Categories.Merge(g.Categories)
+		if err = categories.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid categories for guild %s: %w", guildID, err)
+		}
+		if err = cfg.Tickets.validateForm(categories); err != nil {
+			return nil, fmt.Errorf("invalid ticket form for guild %s: %w", guildID, err)
+		}
+		if err = g.Access.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid access policy for guild %s: %w", guildID, err)
+		}
+		if g.Inactivity != nil {
+			if err = g.Inactivity.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid inactivity policy for guild %s: %w", guildID, err)
+			}
+		}
+		if g.SLA != nil {
+			if err = g.SLA.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid SLA policy for guild %s: %w", guildID, err)
+			}
+		}
+		if g.BusinessHours != nil {
+			schedule, err := g.BusinessHours.Schedule()
+			if err != nil {
+				return nil, fmt.Errorf("invalid business hours for guild %s: %w", guildID, err)
+			}
+			if cfg.schedules == nil {
+				cfg.schedules = make(map[string]*hours.Schedule)
+			}
+			cfg.schedules[guildID] = schedule
+		}
+		for _, format := range g.TranscriptFormats {
+			if _, err = transcript.ParseFormat(format); err != nil {
+				return nil, fmt.Errorf("invalid transcript format for guild %s: %w", guildID, err)
+			}
+		}
+	}
 	return &cfg, nil
 }
 
 type Config struct {
-	Log LogConfig `toml:"log"`
-	Bot BotConfig `toml:"bot"`
+	Log         LogConfig         `toml:"log"`
+	Bot         BotConfig         `toml:"bot"`
+	Database    DatabaseConfig    `toml:"database"`
+	Tickets     TicketsConfig     `toml:"tickets"`
+	Transcripts TranscriptsConfig `toml:"transcripts"`
+	Categories  common.Categories `toml:"categories"`
+	Access      policy.Policy     `toml:"access"`
+	Errors      ErrorsConfig      `toml:"errors"`
+
+	// schedules holds the parsed business hours of the guilds which have them
+	schedules map[string]*hours.Schedule
+}
+
+// ErrorsConfig controls how failed interactions are reported.
+type ErrorsConfig struct {
+	// DiagnosticsChannelID receives the details of every failed interaction, unless the guild has its own
+	DiagnosticsChannelID snowflake.ID `toml:"diagnostics_channel_id"`
+	// Messages replaces the reply members get when an interaction fails, keyed by Discord locale such as "en-US"
+	// or "de"; "default" applies to every other locale. "{id}" is replaced by the failure's correlation ID.
+	Messages map[string]string `toml:"messages"`
 }
 
 type BotConfig struct {
@@ -38,3 +148,194 @@ type LogConfig struct {
 	Format    string     `toml:"format"`
 	AddSource bool       `toml:"add_source"`
 }
+
+type DatabaseConfig struct {
+	Driver string `toml:"driver"`
+	Path   string `toml:"path"`
+}
+
+// TicketsConfig holds the ticket lifecycle defaults, which individual guilds may override.
+type TicketsConfig struct {
+	// ReopenWindowDays is how long after closing a ticket may be reopened; 0 disables reopening
+	ReopenWindowDays int `toml:"reopen_window_days"`
+	// RenameOnClaim appends the assignee's name to the ticket thread's name when it is claimed or assigned
+	RenameOnClaim bool `toml:"rename_on_claim"`
+	// Lengths of the subject and description asked for in the ticket creation form
+	MinSubjectLength int `toml:"min_subject_length"`
+	MaxSubjectLength int `toml:"max_subject_length"`
+	MinContentLength int `toml:"min_content_length"`
+	MaxContentLength int `toml:"max_content_length"`
+	// Inactivity nudges and eventually closes tickets whose opener stopped replying; disabled by default
+	Inactivity common.InactivityPolicy `toml:"inactivity"`
+	// SLA sets the deadlines tickets are escalated against; disabled by default
+	SLA    common.SLAPolicy             `toml:"sla"`
+	Guilds map[string]GuildTicketConfig `toml:"guilds"`
+}
+
+// maxTicketFormLength caps the combined length of a ticket's answers, leaving room in Discord's 2000 character
+// message limit for the rest of the ticket message.
+const maxTicketFormLength = 1500
+
+// validateForm checks the form lengths, and that no category's form can produce a ticket message which is too long.
+func (c TicketsConfig) validateForm(categories common.Categories) error {
+	if c.MinSubjectLength < 1 || c.MinSubjectLength > c.MaxSubjectLength || c.MaxSubjectLength > maxThreadNameLength {
+		return fmt.Errorf("subject lengths must satisfy 1 <= min_subject_length <= max_subject_length <= %d",
+			maxThreadNameLength)
+	}
+	if c.MinContentLength < 1 || c.MinContentLength > c.MaxContentLength {
+		return fmt.Errorf("content lengths must satisfy 1 <= min_content_length <= max_content_length")
+	}
+	for _, category := range categories {
+		if n := c.MaxSubjectLength + c.MaxContentLength + category.FormLength(); n > maxTicketFormLength {
+			return fmt.Errorf(
+				"category %q: the subject, description and fields may add up to %d characters, at most %d are allowed",
+				category.Title, n, maxTicketFormLength,
+			)
+		}
+	}
+	return nil
+}
+
+// GuildTicketConfig overrides TicketsConfig for a single guild, keyed by guild ID. Unset fields inherit the defaults.
+type GuildTicketConfig struct {
+	ReopenWindowDays *int         `toml:"reopen_window_days"`
+	RenameOnClaim    *bool        `toml:"rename_on_claim"`
+	LogChannelID     snowflake.ID `toml:"log_channel_id"`
+	DMTranscripts    *bool        `toml:"dm_transcripts"`
+	// TranscriptFormats replaces the transcript formats rendered for the guild's tickets
+	TranscriptFormats []string `toml:"transcript_formats"`
+	// Inactivity replaces the inactivity policy for the guild's tickets
+	Inactivity *common.InactivityPolicy `toml:"inactivity"`
+	// SLA replaces the SLA policy for the guild's tickets
+	SLA *common.SLAPolicy `toml:"sla"`
+	// BusinessHours are when the guild's support team is around; tickets are answered at any time if unset
+	BusinessHours *hours.Config `toml:"business_hours"`
+	// DiagnosticsChannelID receives the details of the guild's failed interactions
+	DiagnosticsChannelID snowflake.ID `toml:"diagnostics_channel_id"`
+	// Categories override or extend the default categories, matched by title
+	Categories common.Categories `toml:"categories"`
+	// Access replaces the tiers of the access policy which it defines
+	Access policy.Policy `toml:"access"`
+}
+
+// TranscriptsConfig controls the transcripts generated when a ticket is closed.
+type TranscriptsConfig struct {
+	// Formats lists the formats to render, any of "html", "markdown", "json" and "pdf"
+	Formats []string `toml:"formats"`
+	// Directory is where transcripts are stored, one subdirectory per guild; empty disables storing them
+	Directory string `toml:"directory"`
+	// DMOpener sends the transcript to the ticket's opener
+	DMOpener bool `toml:"dm_opener"`
+	// PDFFont is the path of a TrueType font to set PDF transcripts in instead of the built-in DejaVu Sans, for
+	// scripts it lacks such as Chinese, Japanese or Korean
+	PDFFont string `toml:"pdf_font"`
+
+	// font is the loaded PDFFont, if any
+	font *transcript.Font
+}
+
+// Font returns the font PDF transcripts are set in.
+func (c TranscriptsConfig) Font() transcript.Font {
+	if c.font == nil {
+		return transcript.DefaultFont
+	}
+	return *c.font
+}
+
+// ReopenWindow returns how long after closing a ticket in the given guild may be reopened.
+func (c TicketsConfig) ReopenWindow(guildID snowflake.ID) time.Duration {
+	days := c.ReopenWindowDays
+	if g, ok := c.Guilds[guildID.String()]; ok && g.ReopenWindowDays != nil {
+		days = *g.ReopenWindowDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ShouldRenameOnClaim reports whether ticket threads in the given guild are renamed after their assignee.
+func (c TicketsConfig) ShouldRenameOnClaim(guildID snowflake.ID) bool {
+	if g, ok := c.Guilds[guildID.String()]; ok && g.RenameOnClaim != nil {
+		return *g.RenameOnClaim
+	}
+	return c.RenameOnClaim
+}
+
+// LogChannel returns the channel transcripts and other ticket records are posted to in the given guild, or 0 if none.
+func (c TicketsConfig) LogChannel(guildID snowflake.ID) snowflake.ID {
+	return c.Guilds[guildID.String()].LogChannelID
+}
+
+// ShouldDMTranscript reports whether ticket openers in the given guild receive their transcript by DM.
+func (c Config) ShouldDMTranscript(guildID snowflake.ID) bool {
+	if g, ok := c.Tickets.Guilds[guildID.String()]; ok && g.DMTranscripts != nil {
+		return *g.DMTranscripts
+	}
+	return c.Transcripts.DMOpener
+}
+
+// TranscriptFormats returns the formats transcripts of the given guild's tickets are rendered in.
+func (c Config) TranscriptFormats(guildID snowflake.ID) []transcript.Format {
+	names := c.Transcripts.Formats
+	if g, ok := c.Tickets.Guilds[guildID.String()]; ok && g.TranscriptFormats != nil {
+		names = g.TranscriptFormats
+	}
+	formats := make([]transcript.Format, 0, len(names))
+	for _, name := range names {
+		// validated when the config was loaded
+		format, _ := transcript.ParseFormat(name)
+		formats = append(formats, format)
+	}
+	return formats
+}
+
+// InactivityPolicy returns the inactivity policy for tickets of the given guild and category: the category's own,
+// else the guild's, else the default.
+func (c Config) InactivityPolicy(guildID snowflake.ID, category string) common.InactivityPolicy {
+	if cat, ok := c.GuildCategory(guildID, category); ok && cat.Inactivity != nil {
+		return *cat.Inactivity
+	}
+	if g := c.Tickets.Guilds[guildID.String()]; g.Inactivity != nil {
+		return *g.Inactivity
+	}
+	return c.Tickets.Inactivity
+}
+
+// SLAPolicy returns the SLA policy for tickets of the given guild and category: the category's own, else the
+// guild's, else the default.
+func (c Config) SLAPolicy(guildID snowflake.ID, category string) common.SLAPolicy {
+	if cat, ok := c.GuildCategory(guildID, category); ok && cat.SLA != nil {
+		return *cat.SLA
+	}
+	if g := c.Tickets.Guilds[guildID.String()]; g.SLA != nil {
+		return *g.SLA
+	}
+	return c.Tickets.SLA
+}
+
+// BusinessHours returns the business hours of the given guild, or nil if its support team is around at all times.
+func (c Config) BusinessHours(guildID snowflake.ID) *hours.Schedule {
+	return c.schedules[guildID.String()]
+}
+
+// GuildCategories returns the ticket categories of the given guild, including disabled ones.
+func (c Config) GuildCategories(guildID snowflake.ID) common.Categories {
+	return c.Categories.Merge(c.Tickets.Guilds[guildID.String()].Categories)
+}
+
+// GuildCategory returns the category of the given guild with the given title.
+func (c Config) GuildCategory(guildID snowflake.ID, title string) (common.TicketCategory, bool) {
+	return c.GuildCategories(guildID).Find(title)
+}
+
+// GuildPolicy returns the access policy of the given guild.
+func (c Config) GuildPolicy(guildID snowflake.ID) policy.Policy {
+	return c.Access.Override(c.Tickets.Guilds[guildID.String()].Access)
+}
+
+// DiagnosticsChannel returns the channel the details of the given guild's failed interactions are posted to,
+// or 0 if none.
+func (c Config) DiagnosticsChannel(guildID snowflake.ID) snowflake.ID {
+	if id := c.Tickets.Guilds[guildID.String()].DiagnosticsChannelID; id != 0 {
+		return id
+	}
+	return c.Errors.DiagnosticsChannelID
+}
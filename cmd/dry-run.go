@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/commands"
+)
+
+// Report describes the planned changes to the support channel, one per line.
+func (p *SupportChannelPlan) Report() string {
+	var sb strings.Builder
+	if p.ChannelID == 0 {
+		fmt.Fprintf(&sb, "- create text channel #%s with topic %q\n", p.Name, p.Topic)
+		for _, o := range p.Overwrites {
+			fmt.Fprintf(&sb, "- add permission overwrite for %s: %s\n", p.overwriteTarget(o), overwriteSummary(o))
+		}
+		sb.WriteString("- post and pin the ticket panel\n")
+		return sb.String()
+	}
+	if p.NameChanged {
+		fmt.Fprintf(&sb, "- rename channel #%s to #%s\n", p.Channel.Name(), p.Name)
+	}
+	if p.TopicChanged {
+		var topic string
+		if t := p.Channel.Topic(); t != nil {
+			topic = *t
+		}
+		fmt.Fprintf(&sb, "- change topic from %q to %q\n", topic, p.Topic)
+	}
+	if p.TypeChanged {
+		sb.WriteString("- convert channel to a text channel\n")
+	}
+	for _, o := range p.AddedOverwrites {
+		fmt.Fprintf(&sb, "- add permission overwrite for %s: %s\n", p.overwriteTarget(o), overwriteSummary(o))
+	}
+	for _, o := range p.ChangedOverwrites {
+		i := slices.IndexFunc(p.Channel.PermissionOverwrites(), func(c discord.PermissionOverwrite) bool {
+			return sameOverwriteTarget(c, o)
+		})
+		fmt.Fprintf(&sb, "- change permission overwrite for %s from %s to %s\n",
+			p.overwriteTarget(o), overwriteSummary(p.Channel.PermissionOverwrites()[i]), overwriteSummary(o))
+	}
+	for _, o := range p.RemovedOverwrites {
+		fmt.Fprintf(&sb, "- remove permission overwrite for %s: %s\n", p.overwriteTarget(o), overwriteSummary(o))
+	}
+	switch {
+	case p.PanelID == 0:
+		sb.WriteString("- post and pin the ticket panel\n")
+	case p.EditPanel:
+		fmt.Fprintf(&sb, "- edit the ticket panel (message %s)\n", p.PanelID)
+	}
+	if p.PanelID != 0 && p.PinPanel {
+		fmt.Fprintf(&sb, "- pin the ticket panel (message %s)\n", p.PanelID)
+	}
+	if n := len(p.ObsoleteMessages); n > 0 {
+		fmt.Fprintf(&sb, "- delete %d obsolete bot message(s)\n", n)
+	}
+	return sb.String()
+}
+
+// overwriteTarget names the role or member a permission overwrite applies to.
+func (p *SupportChannelPlan) overwriteTarget(o discord.PermissionOverwrite) string {
+	if o.Type() == discord.PermissionOverwriteTypeMember {
+		return fmt.Sprintf("member %s", o.ID())
+	}
+	if o.ID() == p.GuildID {
+		return "@everyone"
+	}
+	if i := slices.IndexFunc(p.roles, func(r discord.Role) bool { return r.ID == o.ID() }); i >= 0 {
+		return fmt.Sprintf("role @%s (%s)", p.roles[i].Name, o.ID())
+	}
+	return fmt.Sprintf("role %s", o.ID())
+}
+
+// overwriteSummary shows the permissions a permission overwrite allows and denies. Permissions are shown as their
+// integer value, as Discord documents them, since not every bit is known by name.
+func overwriteSummary(o discord.PermissionOverwrite) string {
+	allow, deny := overwritePermissions(o)
+	return fmt.Sprintf("allow %d, deny %d", allow, deny)
+}
+
+// CommandChanges is the difference between the commands the bot defines and those registered with Discord in
+// one scope.
+type CommandChanges struct {
+	// GuildID is the development guild the commands are registered in, or 0 for global commands
+	GuildID snowflake.ID
+	Added   []string
+	Changed []string
+	Removed []string
+}
+
+// Empty reports whether the registered commands already match the bot's.
+func (c CommandChanges) Empty() bool {
+	return len(c.Added)+len(c.Changed)+len(c.Removed) == 0
+}
+
+// Report describes the changes syncing the commands would make, one per line.
+func (c CommandChanges) Report() string {
+	var sb strings.Builder
+	for _, name := range c.Added {
+		fmt.Fprintf(&sb, "- register /%s\n", name)
+	}
+	for _, name := range c.Changed {
+		fmt.Fprintf(&sb, "- update /%s\n", name)
+	}
+	for _, name := range c.Removed {
+		fmt.Fprintf(&sb, "- remove /%s\n", name)
+	}
+	return sb.String()
+}
+
+// PlanCommands compares the bot's commands with those registered in each scope they are synced to: the
+// development guilds if any are configured, otherwise globally.
+func PlanCommands(b *Bot) ([]CommandChanges, error) {
+	scopes := b.Cfg.Bot.DevGuilds
+	if len(scopes) == 0 {
+		scopes = []snowflake.ID{0}
+	}
+	changes := make([]CommandChanges, 0, len(scopes))
+	for _, guildID := range scopes {
+		var (
+			registered []discord.ApplicationCommand
+			err        error
+		)
+		if guildID == 0 {
+			registered, err = b.Client.Rest().GetGlobalCommands(b.Client.ApplicationID(), false)
+		} else {
+			registered, err = b.Client.Rest().GetGuildCommands(b.Client.ApplicationID(), guildID, false)
+		}
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to get registered commands")
+		}
+		c := diffCommands(commands.Commands, registered)
+		c.GuildID = guildID
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+func diffCommands(want []discord.ApplicationCommandCreate, have []discord.ApplicationCommand) CommandChanges {
+	var c CommandChanges
+	for _, w := range want {
+		i := slices.IndexFunc(have, func(h discord.ApplicationCommand) bool {
+			return h.Name() == w.CommandName() && h.Type() == w.Type()
+		})
+		if i < 0 {
+			c.Added = append(c.Added, w.CommandName())
+		} else if !commandMatches(w, have[i]) {
+			c.Changed = append(c.Changed, w.CommandName())
+		}
+	}
+	for _, h := range have {
+		if !slices.ContainsFunc(want, func(w discord.ApplicationCommandCreate) bool {
+			return h.Name() == w.CommandName() && h.Type() == w.Type()
+		}) {
+			c.Removed = append(c.Removed, h.Name())
+		}
+	}
+	return c
+}
+
+// commandMatches reports whether the registered command has every field the bot defines for it. Both are compared
+// as JSON, since Discord adds fields of its own such as IDs and versions.
+func commandMatches(want discord.ApplicationCommandCreate, have discord.ApplicationCommand) bool {
+	var w, h any
+	if !decodeJSON(want, &w) || !decodeJSON(have, &h) {
+		return false
+	}
+	return jsonSubset(w, h)
+}
+
+func decodeJSON(v any, dst *any) bool {
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Debug("Failed to encode command", slog.Any("err", err))
+		return false
+	}
+	return json.Unmarshal(data, dst) == nil
+}
+
+// jsonSubset reports whether every field set in want has the same value in have. Fields left at their zero value
+// in want may be missing from have, as Discord omits defaults.
+func jsonSubset(want any, have any) bool {
+	switch w := want.(type) {
+	case map[string]any:
+		h, ok := have.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range w {
+			hv, ok := h[k]
+			if !ok {
+				if !isZeroJSON(v) {
+					return false
+				}
+				continue
+			}
+			if !jsonSubset(v, hv) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		h, ok := have.([]any)
+		if !ok || len(h) != len(w) {
+			return isZeroJSON(w) && isZeroJSON(have)
+		}
+		for i := range w {
+			if !jsonSubset(w[i], h[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(want, have) || isZeroJSON(want) && isZeroJSON(have)
+	}
+}
+
+func isZeroJSON(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// DryRun writes the changes the bot would make to the support channel of every guild it is in, and to its commands,
+// without making any of them.
+func DryRun(b *Bot, w io.Writer) error {
+	guilds, err := botGuilds(b)
+	if err != nil {
+		return err
+	}
+	for _, g := range guilds {
+		fmt.Fprintf(w, "Guild %s (%s):\n", g.Name, g.ID)
+		p, err := PlanSupportChannel(b, g.ID, 0)
+		if err != nil {
+			fmt.Fprintf(w, "- failed to plan support channel: %s\n", err)
+			continue
+		}
+		fmt.Fprint(w, reportOrNone(p.Report()))
+	}
+	changes, err := PlanCommands(b)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		fmt.Fprintf(w, "%s:\n%s", CommandScope(c.GuildID), reportOrNone(c.Report()))
+	}
+	return nil
+}
+
+// PreviewGuild describes the changes the bot would make to the guild's support channel and to its commands.
+func PreviewGuild(b *Bot, guildID snowflake.ID) (string, error) {
+	p, err := PlanSupportChannel(b, guildID, 0)
+	if err != nil {
+		return "", err
+	}
+	changes, err := PlanCommands(b)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Support channel:\n%s", reportOrNone(p.Report()))
+	for _, c := range changes {
+		if c.GuildID == 0 || c.GuildID == guildID {
+			fmt.Fprintf(&sb, "%s:\n%s", CommandScope(c.GuildID), reportOrNone(c.Report()))
+		}
+	}
+	return sb.String(), nil
+}
+
+// CommandScope names where commands are registered.
+func CommandScope(guildID snowflake.ID) string {
+	if guildID == 0 {
+		return "Global commands"
+	}
+	return fmt.Sprintf("Commands in development guild %s", guildID)
+}
+
+func reportOrNone(report string) string {
+	if report == "" {
+		return "- no changes\n"
+	}
+	return report
+}
+
+// guildsPageSize is the most guilds Discord returns at once.
+const guildsPageSize = 200
+
+// botGuilds lists every guild the bot is in, without needing the gateway.
+func botGuilds(b *Bot) ([]discord.OAuth2Guild, error) {
+	var (
+		guilds []discord.OAuth2Guild
+		after  snowflake.ID
+	)
+	for {
+		page, err := b.Client.Rest().GetCurrentUserGuilds("", 0, after, guildsPageSize, false)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to get guilds")
+		}
+		guilds = append(guilds, page...)
+		if len(page) < guildsPageSize {
+			return guilds, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
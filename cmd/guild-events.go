@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// ReconcileDelay is how long a guild has to stay quiet after a change before its support channel is reconciled,
+// so that a burst of changes, such as an admin reordering roles, leads to a single reconciliation.
+const ReconcileDelay = 5 * time.Second
+
+// ReconcileQueue reconciles guilds' support channels a while after the changes which call for it.
+type ReconcileQueue struct {
+	mu      sync.Mutex
+	delay   time.Duration
+	pending map[snowflake.ID]*time.Timer
+	run     func(guildID snowflake.ID)
+}
+
+// NewReconcileQueue returns a queue which calls run once a guild has stayed quiet for delay.
+func NewReconcileQueue(delay time.Duration, run func(guildID snowflake.ID)) *ReconcileQueue {
+	return &ReconcileQueue{delay: delay, pending: make(map[snowflake.ID]*time.Timer), run: run}
+}
+
+// Schedule reconciles the guild's support channel once the guild has stayed quiet, restarting the wait if a
+// reconciliation is already pending.
+func (q *ReconcileQueue) Schedule(guildID snowflake.ID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if t, ok := q.pending[guildID]; ok {
+		t.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(q.delay, func() {
+		q.mu.Lock()
+		if q.pending[guildID] != t {
+			q.mu.Unlock()
+			return
+		}
+		delete(q.pending, guildID)
+		q.mu.Unlock()
+		q.run(guildID)
+	})
+	q.pending[guildID] = t
+}
+
+// Cancel drops the guild's pending reconciliation, if any.
+func (q *ReconcileQueue) Cancel(guildID snowflake.ID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if t, ok := q.pending[guildID]; ok {
+		t.Stop()
+		delete(q.pending, guildID)
+	}
+}
+
+// reconcileGuild is run by the bot's ReconcileQueue.
+func (b *Bot) reconcileGuild(guildID snowflake.ID) {
+	if b.Client.Caches().IsGuildUnavailable(guildID) {
+		slog.Warn("Guild is unavailable", slog.Any("guild_id", guildID))
+		return
+	}
+	slog.Info("Reconciling support channel after guild changes", slog.Any("guild_id", guildID))
+	if err := ReconcileSupportChannel(b, guildID, 0); err != nil {
+		slog.Error("Failed to reconcile support channel", slog.Any("guild_id", guildID), slog.Any("err", err))
+	}
+}
+
+// GuildChangeListener keeps each guild's support channel in line with the guild: role changes can change who
+// may see it, and the channel itself may be edited or deleted by hand. Leaving a guild forgets everything stored
+// for it.
+func (b *Bot) GuildChangeListener() bot.EventListener {
+	return bot.NewListenerFunc(func(e bot.Event) {
+		switch e := e.(type) {
+		case *events.RoleCreate:
+			b.Reconciles.Schedule(e.GuildID)
+		case *events.RoleUpdate:
+			b.Reconciles.Schedule(e.GuildID)
+		case *events.RoleDelete:
+			b.Reconciles.Schedule(e.GuildID)
+		case *events.GuildChannelUpdate:
+			// the bot's own updates leave the channel as it should be, so they are not reconciled again
+			if b.isSupportChannel(e.GuildID, e.Channel) && supportChannelDrifted(b, e.GuildID, e.Channel) {
+				b.Reconciles.Schedule(e.GuildID)
+			}
+		case *events.GuildChannelDelete:
+			if b.isSupportChannel(e.GuildID, e.Channel) {
+				b.Reconciles.Schedule(e.GuildID)
+			}
+		case *events.GuildLeave:
+			b.OnLeave(e)
+		}
+	})
+}
+
+// isSupportChannel reports whether the channel is the guild's support channel.
+func (b *Bot) isSupportChannel(guildID snowflake.ID, c discord.GuildChannel) bool {
+	return c != nil && c.ID() == GuildSettings(b, guildID).SupportChannelID
+}
+
+// OnLeave forgets everything the bot holds for a guild it was removed from, including its tickets. Guilds which are
+// merely unavailable during an outage are not left and keep their data.
+func (b *Bot) OnLeave(e *events.GuildLeave) {
+	b.Reconciles.Cancel(e.GuildID)
+	b.Drafts.ForgetGuild(e.GuildID)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.Store.DeleteGuild(ctx, e.GuildID); err != nil {
+		slog.Error("Failed to delete data of left guild", slog.Any("guild_id", e.GuildID), slog.Any("err", err))
+		return
+	}
+	slog.Info("Left guild, its data was deleted", slog.Any("guild_id", e.GuildID))
+}
@@ -1,14 +1,28 @@
 package handlers
 
 import (
+	"context"
+	"log/slog"
+	"time"
+
 	"github.com/disgoorg/disgo/bot"
 	"github.com/disgoorg/disgo/events"
 
 	"github.com/kapparina/ticketsplease/cmd"
 )
 
+// MessageHandler records activity in ticket threads, such as who last replied and how quickly staff responded.
 func MessageHandler(b *cmd.Bot) bot.EventListener {
 	return bot.NewListenerFunc(func(e *events.MessageCreate) {
-		// TODO: handle message
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := cmd.TrackTicketActivity(ctx, b, e.Message); err != nil {
+			slog.Error(
+				"Failed to track ticket activity",
+				slog.Any("channel_id", e.ChannelID),
+				slog.Any("message_id", e.MessageID),
+				slog.Any("err", err),
+			)
+		}
 	})
 }
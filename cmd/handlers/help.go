@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+
+	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/handler"
 
 	"github.com/kapparina/ticketsplease/cmd"
@@ -10,10 +13,15 @@ import (
 
 func HelpHandler(b *cmd.Bot) handler.CommandHandler {
 	return func(e *handler.CommandEvent) error {
-		helpData := templates.HelpData{CommandName: commands.Ticket.CommandName()}
-		if err := cmd.PostHelpMessage(b, nil, helpData, e); err != nil {
-			return err
-		}
-		return nil
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			content, err := templates.PopulateEphemeralHelpData(templates.HelpData{
+				CommandName: commands.TicketCreateCommandName,
+				Version:     b.GitTag,
+			})
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Reply(content), nil
+		})
 	}
 }
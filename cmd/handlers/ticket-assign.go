@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+)
+
+// AssignTicketHandler creates a command handler which assigns the ticket hosted in the current thread
+// to a member of its support team, replacing any current assignee
+func AssignTicketHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		data := e.SlashCommandInteractionData()
+		assignee := data.Member("member")
+		assignee.User = data.User("member")
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetStaffTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.AssignTicket(ctx, b, t, e.User().ID, assignee.Member, true); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Assigned ticket #%d to %s.", t.Number, assignee.Mention()), nil
+		})
+	}
+}
+
+// UnassignTicketHandler creates a command handler which removes the assignee of the ticket hosted in the current thread
+func UnassignTicketHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetStaffTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.UnassignTicket(ctx, b, t, e.User().ID); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Ticket #%d is no longer assigned.", t.Number), nil
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+	"github.com/kapparina/ticketsplease/cmd/common"
+)
+
+// maxAutocompleteChoices is the most choices Discord accepts in an autocomplete response.
+const maxAutocompleteChoices = 25
+
+// TicketCategoryAutocompleteHandler suggests the categories enabled in the guild the ticket is created in.
+func TicketCategoryAutocompleteHandler(b *cmd.Bot) handler.AutocompleteHandler {
+	return func(e *handler.AutocompleteEvent) error {
+		categories := b.Cfg.Categories
+		if guildID := e.GuildID(); guildID != nil {
+			categories = cmd.EnabledCategories(b, *guildID)
+		}
+		return autocompleteCategories(e, categories)
+	}
+}
+
+// autocompleteCategories responds with the categories matching what has been typed into the category option.
+func autocompleteCategories(e *handler.AutocompleteEvent, categories common.Categories) error {
+	options, err := common.GetCategoryChoices[discord.AutocompleteChoiceString](categories)
+	if err != nil {
+		return err
+	}
+	options = common.GetFilteredAutocompleteOptions(e.Data.String("category"), options)
+	choices := make([]discord.AutocompleteChoice, 0, min(len(options), maxAutocompleteChoices))
+	for _, o := range options[:min(len(options), maxAutocompleteChoices)] {
+		choices = append(choices, o)
+	}
+	return e.AutocompleteResult(choices)
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+)
+
+// CloseTicketHandler creates a command handler which closes the ticket hosted in the current thread
+func CloseTicketHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		reason := e.SlashCommandInteractionData().String("reason")
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetManageableTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.CloseTicket(ctx, b, t, e.User().ID, reason); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Closed ticket #%d.", t.Number), nil
+		})
+	}
+}
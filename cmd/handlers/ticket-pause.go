@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+)
+
+// PauseTicketHandler creates a command handler which pauses inactivity reminders and automatic closure of the ticket
+// hosted in the current thread
+func PauseTicketHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		reason := e.SlashCommandInteractionData().String("reason")
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetStaffTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.PauseInactivity(ctx, b, t, e.User().ID, reason); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Paused inactivity reminders for ticket #%d.", t.Number), nil
+		})
+	}
+}
+
+// ResumeTicketHandler creates a command handler which resumes inactivity reminders and automatic closure of the
+// ticket hosted in the current thread
+func ResumeTicketHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetStaffTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.ResumeInactivity(ctx, b, t, e.User().ID); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Resumed inactivity reminders for ticket #%d.", t.Number), nil
+		})
+	}
+}
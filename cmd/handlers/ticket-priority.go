@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+	"github.com/kapparina/ticketsplease/cmd/common"
+)
+
+// PriorityTicketHandler creates a command handler which sets the priority of the ticket hosted in the current thread
+func PriorityTicketHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		priority := common.Priority(e.SlashCommandInteractionData().String("level"))
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetStaffTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.SetTicketPriority(ctx, b, t, e.User().ID, priority); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Ticket #%d is now %s priority.", t.Number, priority), nil
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+// ReopenTicketHandler creates a command handler which reopens a closed ticket,
+// either by number or the one hosted in the current thread
+func ReopenTicketHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		number, byNumber := e.SlashCommandInteractionData().OptInt("number")
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			var (
+				t   *store.Ticket
+				err error
+			)
+			if byNumber {
+				t, err = cmd.GetManageableTicketByNumber(ctx, b, *e.GuildID(), int64(number), e.Member())
+			} else {
+				t, err = cmd.GetManageableTicket(ctx, b, e.Channel().ID(), e.Member())
+			}
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.ReopenTicket(ctx, b, t, e.User().ID); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Replyf("Reopened ticket #%d: <#%s>", t.Number, t.ThreadID), nil
+		})
+	}
+}
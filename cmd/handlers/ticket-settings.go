@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd"
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+// ShowSettingsHandler creates a command handler which shows the guild's ticket settings
+func ShowSettingsHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			settings, err := b.Store.GetGuildSettings(ctx, *e.GuildID())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return settingsReply(b, *settings, "")
+		})
+	}
+}
+
+// SupportChannelSettingsHandler creates a command handler which renames the support channel or changes its topic
+func SupportChannelSettingsHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		data := e.SlashCommandInteractionData()
+		guildID := *e.GuildID()
+		return updateSettings(b, e, func(s *store.GuildSettings) (string, error) {
+			if name, ok := data.OptString("name"); ok {
+				s.SupportChannelName = normaliseChannelName(name)
+			}
+			if topic, ok := data.OptString("topic"); ok {
+				s.SupportChannelTopic = topic
+			}
+			return "Support channel updated.", nil
+		}, func() error {
+			return cmd.ReconcileSupportChannel(b, guildID, 0)
+		})
+	}
+}
+
+// LogChannelSettingsHandler creates a command handler which sets the channel transcripts are posted to
+func LogChannelSettingsHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		data := e.SlashCommandInteractionData()
+		return updateSettings(b, e, func(s *store.GuildSettings) (string, error) {
+			s.LogChannelID = 0
+			if channel, ok := data.OptChannel("channel"); ok {
+				s.LogChannelID = channel.ID
+			}
+			return "Log channel updated.", nil
+		}, nil)
+	}
+}
+
+// AddStaffRoleHandler creates a command handler which adds a staff role
+func AddStaffRoleHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		role := e.SlashCommandInteractionData().Role("role")
+		return updateSettings(b, e, func(s *store.GuildSettings) (string, error) {
+			if role.ID == *e.GuildID() || role.Managed {
+				return "That role cannot be a staff role.", errSettingsUnchanged
+			}
+			if slices.Contains(s.StaffRoleIDs, role.ID) {
+				return "That role already is a staff role.", errSettingsUnchanged
+			}
+			s.StaffRoleIDs = append(s.StaffRoleIDs, role.ID)
+			return "Staff role added.", nil
+		}, nil)
+	}
+}
+
+// RemoveStaffRoleHandler creates a command handler which removes a staff role
+func RemoveStaffRoleHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		role := e.SlashCommandInteractionData().Role("role")
+		return updateSettings(b, e, func(s *store.GuildSettings) (string, error) {
+			i := slices.Index(s.StaffRoleIDs, role.ID)
+			if i < 0 {
+				return "That role is not a staff role.", errSettingsUnchanged
+			}
+			s.StaffRoleIDs = slices.Delete(s.StaffRoleIDs, i, i+1)
+			return "Staff role removed.", nil
+		}, nil)
+	}
+}
+
+// CategorySettingsHandler creates a command handler which enables or disables a ticket category
+func CategorySettingsHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		data := e.SlashCommandInteractionData()
+		title := data.String("category")
+		return updateSettings(b, e, func(s *store.GuildSettings) (string, error) {
+			if _, ok := b.Cfg.GuildCategories(*e.GuildID()).Enabled().Find(title); !ok {
+				return fmt.Sprintf(
+					"`%s` is not a ticket category here; please pick one of the suggested categories.", title,
+				), errSettingsUnchanged
+			}
+			s.DisabledCategories = slices.DeleteFunc(s.DisabledCategories, func(c string) bool { return c == title })
+			if !data.Bool("enabled") {
+				s.DisabledCategories = append(s.DisabledCategories, title)
+				return "Category disabled.", nil
+			}
+			return "Category enabled.", nil
+		}, nil)
+	}
+}
+
+// CategorySettingsAutocompleteHandler suggests the categories configured for the guild, enabled or not
+func CategorySettingsAutocompleteHandler(b *cmd.Bot) handler.AutocompleteHandler {
+	return func(e *handler.AutocompleteEvent) error {
+		return autocompleteCategories(e, b.Cfg.GuildCategories(*e.GuildID()))
+	}
+}
+
+// PingSettingsHandler creates a command handler which chooses whom tickets ping
+func PingSettingsHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		mode := store.PingMode(e.SlashCommandInteractionData().String("mode"))
+		return updateSettings(b, e, func(s *store.GuildSettings) (string, error) {
+			if !slices.Contains(store.PingModes, mode) {
+				return "That is not a ping mode.", errSettingsUnchanged
+			}
+			s.PingMode = mode
+			return "Ping behaviour updated.", nil
+		}, nil)
+	}
+}
+
+// LimitSettingsHandler creates a command handler which limits how many tickets each member may have open
+func LimitSettingsHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		limit := e.SlashCommandInteractionData().Int("max_open")
+		return updateSettings(b, e, func(s *store.GuildSettings) (string, error) {
+			s.MaxOpenTickets = limit
+			return "Ticket limit updated.", nil
+		}, nil)
+	}
+}
+
+// ResetSettingsHandler creates a command handler which resets the guild's ticket settings to the defaults
+func ResetSettingsHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		guildID := *e.GuildID()
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			// resetting forgets the support channel too, so look it up beforehand to keep it
+			channelID, err := cmd.GetSupportChannel(b, &guildID)
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = b.Store.DeleteGuildSettings(ctx, guildID); err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if err = cmd.ReconcileSupportChannel(b, guildID, channelID); err != nil {
+				return discord.MessageUpdate{}, errors.WithMessage(err, "failed to apply support channel settings")
+			}
+			return settingsReply(b, store.GuildSettings{GuildID: guildID}, "Settings reset to the defaults.")
+		})
+	}
+}
+
+// previewFileThreshold is the report length above which the preview is attached as a file, as it would not fit
+// into a message.
+const previewFileThreshold = 1900
+
+// PreviewSettingsHandler creates a command handler which shows the changes the bot would make to the guild's
+// support channel and to its commands, without making them
+func PreviewSettingsHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			report, err := cmd.PreviewGuild(b, *e.GuildID())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			if len(report) > previewFileThreshold {
+				return discord.NewMessageUpdateBuilder().
+					SetContent("The planned changes are attached.").
+					AddFile("planned-changes.txt", "", strings.NewReader(report)).
+					Build(), nil
+			}
+			return discord.NewMessageUpdateBuilder().
+				SetContentf("```\n%s```", strings.ReplaceAll(report, "```", "'''")).
+				SetAllowedMentions(&discord.AllowedMentions{}).
+				Build(), nil
+		})
+	}
+}
+
+// normaliseChannelName converts a name to the form Discord gives text channels, so that the stored name matches
+// the channel's and the support channel is not renamed again on every reconciliation.
+func normaliseChannelName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
+
+// errSettingsUnchanged is returned by settings mutations which decline the change; their message is shown as is.
+var errSettingsUnchanged = errors.New("settings unchanged")
+
+// updateSettings defers the reply, then loads the guild's settings, applies mutate, stores the result, runs apply
+// (if any) to bring Discord in line with the new settings, and replies with mutate's message and the updated settings.
+func updateSettings(
+	b *cmd.Bot,
+	e *handler.CommandEvent,
+	mutate func(s *store.GuildSettings) (string, error),
+	apply func() error,
+) error {
+	return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+		settings, err := b.Store.GetGuildSettings(ctx, *e.GuildID())
+		if err != nil {
+			return discord.MessageUpdate{}, err
+		}
+		msg, err := mutate(settings)
+		if errors.Is(err, errSettingsUnchanged) {
+			return cmd.Reply(msg), nil
+		} else if err != nil {
+			return discord.MessageUpdate{}, err
+		}
+		if err = b.Store.SaveGuildSettings(ctx, settings); err != nil {
+			return discord.MessageUpdate{}, err
+		}
+		if apply != nil {
+			if err = apply(); err != nil {
+				return discord.MessageUpdate{}, errors.WithMessage(err, "failed to apply settings")
+			}
+		}
+		return settingsReply(b, *settings, msg)
+	})
+}
+
+// settingsReply builds a reply showing the guild's settings, preceded by an optional message
+func settingsReply(b *cmd.Bot, settings store.GuildSettings, msg string) (discord.MessageUpdate, error) {
+	content, err := cmd.PopulateSettingsContent(b, settings)
+	if err != nil {
+		return discord.MessageUpdate{}, err
+	}
+	if msg != "" {
+		content = msg + "\n\n" + content
+	}
+	return discord.NewMessageUpdateBuilder().
+		SetContent(content).
+		SetAllowedMentions(&discord.AllowedMentions{}).
+		Build(), nil
+}
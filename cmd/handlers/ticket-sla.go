@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+)
+
+// SLATicketHandler creates a command handler which shows how the ticket hosted in the current thread stands against
+// its SLA deadlines
+func SLATicketHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetStaffTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			content, err := cmd.PopulateSLAContent(b, t, time.Now().UTC())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.Reply(content), nil
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+	"github.com/kapparina/ticketsplease/cmd/transcript"
+)
+
+// TranscriptTicketHandler creates a command handler which sends the support team a transcript of the ticket hosted
+// in the current thread, in the requested format
+func TranscriptTicketHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		format := transcript.Format(e.SlashCommandInteractionData().String("format"))
+		if format == "" {
+			format = transcript.FormatPDF
+		}
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			t, err := cmd.GetStaffTicket(ctx, b, e.Channel().ID(), e.Member())
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.TranscriptReply(ctx, b, t, format)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+
+	"github.com/kapparina/ticketsplease/cmd"
+	"github.com/kapparina/ticketsplease/cmd/commands"
+	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+// ListTicketsHandler creates a command handler which lists the guild's tickets matching the given filters, limited
+// to the categories the member supports
+func ListTicketsHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		data := e.SlashCommandInteractionData()
+		now := time.Now().UTC()
+		filter := store.TicketFilter{
+			GuildID:  *e.GuildID(),
+			Category: data.String("category"),
+			Status:   store.TicketStatus(data.String("status")),
+			Priority: common.Priority(data.String("priority")),
+		}
+		switch filter.Status {
+		case "":
+			filter.Status = store.TicketStatusOpen
+		case commands.TicketsStatusAll:
+			filter.Status = ""
+		}
+		if assignee, ok := data.OptUser("assignee"); ok {
+			filter.AssigneeID = assignee.ID
+		}
+		if opener, ok := data.OptUser("opener"); ok {
+			filter.OpenerID = opener.ID
+		}
+		if hours, ok := data.OptInt("age"); ok {
+			filter.CreatedBefore = now.Add(-time.Duration(hours) * time.Hour)
+		}
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			return cmd.StaffTicketListReply(ctx, b, e.ID().String(), e.User().ID, e.Member().Member, filter)
+		})
+	}
+}
+
+// MyTicketsHandler creates a command handler which lists the member's own open and recently closed tickets
+func MyTicketsHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			tickets, err := cmd.ListMemberTickets(ctx, b, *e.GuildID(), e.User().ID)
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.TicketListReply(b, e.ID().String(), e.User().ID, "Your tickets", tickets, time.Now().UTC()), nil
+		})
+	}
+}
+
+// SearchTicketsHandler creates a command handler which searches the tickets of the categories the member supports
+func SearchTicketsHandler(b *cmd.Bot) handler.CommandHandler {
+	return func(e *handler.CommandEvent) error {
+		query := e.SlashCommandInteractionData().String("query")
+		return cmd.DeferReply(e.Ctx, e, func(ctx context.Context) (discord.MessageUpdate, error) {
+			results, err := cmd.SearchTickets(ctx, b, *e.GuildID(), e.Member().Member, query)
+			if err != nil {
+				return discord.MessageUpdate{}, err
+			}
+			return cmd.TicketSearchReply(b, e.ID().String(), e.User().ID, query, results, time.Now().UTC()), nil
+		})
+	}
+}
+
+// TicketSearchAutocompleteHandler suggests recent tickets matching what has been typed into the search query.
+func TicketSearchAutocompleteHandler(b *cmd.Bot) handler.AutocompleteHandler {
+	return func(e *handler.AutocompleteEvent) error {
+		suggestions, err := cmd.SearchSuggestions(e.Ctx, b, *e.GuildID(), e.Member().Member, e.Data.String("query"))
+		if err != nil {
+			return err
+		}
+		choices := make([]discord.AutocompleteChoice, 0, min(len(suggestions), maxAutocompleteChoices))
+		for _, s := range suggestions[:min(len(suggestions), maxAutocompleteChoices)] {
+			choices = append(choices, s)
+		}
+		return e.AutocompleteResult(choices)
+	}
+}
@@ -0,0 +1,271 @@
+package hours
+
+import (
+	"cmp"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	// the container image has no time zone database of its own
+	_ "time/tzdata"
+
+	"github.com/pkg/errors"
+)
+
+// searchLimit bounds how far ahead the next opening is looked for, so that a schedule which never opens again, such
+// as one covered by a holiday without end, cannot loop forever.
+const searchLimit = 2 * 366 * 24 * time.Hour
+
+// Config describes a guild's business hours: for each weekday, a comma-separated list of "HH:MM-HH:MM" windows in
+// the guild's time zone, e.g. "09:00-12:00, 13:00-17:00". Days left empty are closed.
+type Config struct {
+	// TimeZone is an IANA time zone such as "Europe/London"; defaults to UTC
+	TimeZone  string `toml:"time_zone"`
+	Monday    string `toml:"monday"`
+	Tuesday   string `toml:"tuesday"`
+	Wednesday string `toml:"wednesday"`
+	Thursday  string `toml:"thursday"`
+	Friday    string `toml:"friday"`
+	Saturday  string `toml:"saturday"`
+	Sunday    string `toml:"sunday"`
+	// Holidays is the path of an iCalendar (.ics) file whose events are treated as closed
+	Holidays string `toml:"holidays"`
+}
+
+// days returns the configured windows indexed by time.Weekday.
+func (c Config) days() [7]string {
+	return [7]string{c.Sunday, c.Monday, c.Tuesday, c.Wednesday, c.Thursday, c.Friday, c.Saturday}
+}
+
+// Schedule parses the business hours and loads their holiday calendar.
+func (c Config) Schedule() (*Schedule, error) {
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	s := &Schedule{loc: loc}
+	open := false
+	for day, spec := range c.days() {
+		if s.week[day], err = parseWindows(spec); err != nil {
+			return nil, errors.WithMessage(err, strings.ToLower(time.Weekday(day).String()))
+		}
+		open = open || len(s.week[day]) > 0
+	}
+	if !open {
+		return nil, errors.New("business hours must open on at least one day")
+	}
+	if c.Holidays != "" {
+		f, err := os.Open(c.Holidays)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to open holiday calendar")
+		}
+		defer f.Close()
+		if s.holidays, err = ParseICS(f, loc); err != nil {
+			return nil, errors.WithMessagef(err, "failed to read holiday calendar %s", c.Holidays)
+		}
+	}
+	return s, nil
+}
+
+// window is a span of a day, as offsets from its midnight.
+type window struct {
+	from, to time.Duration
+}
+
+func parseWindows(spec string) ([]window, error) {
+	var windows []window
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fromSpec, toSpec, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, errors.Errorf("window %q must look like 09:00-17:00", part)
+		}
+		from, err := parseClock(fromSpec)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseClock(toSpec)
+		if err != nil {
+			return nil, err
+		}
+		if from >= to {
+			return nil, errors.Errorf("window %q must end after it starts", part)
+		}
+		windows = append(windows, window{from: from, to: to})
+	}
+	slices.SortFunc(windows, func(a, b window) int { return cmp.Compare(a.from, b.from) })
+	for i := 1; i < len(windows); i++ {
+		if windows[i].from < windows[i-1].to {
+			return nil, errors.New("windows must not overlap")
+		}
+	}
+	return windows, nil
+}
+
+// parseClock parses a time of day such as "09:30"; "24:00" stands for the end of the day.
+func parseClock(spec string) (time.Duration, error) {
+	spec = strings.TrimSpace(spec)
+	hh, mm, ok := strings.Cut(spec, ":")
+	h, errH := strconv.Atoi(hh)
+	m, errM := strconv.Atoi(mm)
+	if !ok || errH != nil || errM != nil || h < 0 || m < 0 || m > 59 || h > 24 || h == 24 && m != 0 {
+		return 0, errors.Errorf("time %q must look like 09:00", spec)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// Holiday is a span of time during which support is closed.
+type Holiday struct {
+	Name       string
+	Start, End time.Time
+	// Yearly holidays recur on the same date every year from Start on
+	Yearly bool
+}
+
+// occurrences returns the holiday's spans around t: itself, or for yearly holidays, the occurrences from the year
+// before t's to the year after.
+func (h Holiday) occurrences(t time.Time) []Holiday {
+	if !h.Yearly {
+		return []Holiday{h}
+	}
+	var spans []Holiday
+	for years := t.Year() - h.Start.Year() - 1; years <= t.Year()-h.Start.Year()+1; years++ {
+		if years >= 0 {
+			spans = append(spans, Holiday{Name: h.Name, Start: h.Start.AddDate(years, 0, 0), End: h.End.AddDate(years, 0, 0)})
+		}
+	}
+	return spans
+}
+
+// Schedule tells when support is open. Every method treats a nil Schedule as always open.
+type Schedule struct {
+	loc      *time.Location
+	week     [7][]window
+	holidays []Holiday
+}
+
+// Location returns the schedule's time zone.
+func (s *Schedule) Location() *time.Location {
+	if s == nil {
+		return time.UTC
+	}
+	return s.loc
+}
+
+// Open reports whether t falls within business hours and not on a holiday.
+func (s *Schedule) Open(t time.Time) bool {
+	if s == nil {
+		return true
+	}
+	_, open := s.windowEnd(t)
+	_, holiday := s.holidayEnd(t)
+	return open && !holiday
+}
+
+// NextOpen returns the first time at or after t when support is open. It reports false if support does not open
+// again within two years.
+func (s *Schedule) NextOpen(t time.Time) (time.Time, bool) {
+	if s == nil {
+		return t, true
+	}
+	limit := t.Add(searchLimit)
+	for t.Before(limit) {
+		if end, ok := s.holidayEnd(t); ok {
+			t = end
+			continue
+		}
+		if _, ok := s.windowEnd(t); ok {
+			return t, true
+		}
+		next, ok := s.nextWindowStart(t)
+		if !ok {
+			return time.Time{}, false
+		}
+		t = next
+	}
+	return time.Time{}, false
+}
+
+// Add returns the time at which d of business hours will have passed since start. Without business hours in
+// the next two years, time is counted as if support never closed.
+func (s *Schedule) Add(start time.Time, d time.Duration) time.Time {
+	if s == nil {
+		return start.Add(d)
+	}
+	t := start
+	for {
+		var ok bool
+		if t, ok = s.NextOpen(t); !ok {
+			return start.Add(d)
+		}
+		end, _ := s.windowEnd(t)
+		if h, ok := s.nextHolidayStart(t); ok && h.Before(end) {
+			end = h
+		}
+		if !t.Add(d).After(end) {
+			return t.Add(d)
+		}
+		d -= end.Sub(t)
+		t = end
+	}
+}
+
+// clock returns the time on the given date at the given offset from its midnight, by the wall clock, so that
+// windows keep their hours across daylight saving changes.
+func (s *Schedule) clock(date time.Time, offset time.Duration) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, int(offset/time.Minute), 0, 0, s.loc)
+}
+
+// windowEnd returns the end of the business hours window covering t, if any.
+func (s *Schedule) windowEnd(t time.Time) (time.Time, bool) {
+	t = t.In(s.loc)
+	for _, w := range s.week[t.Weekday()] {
+		if from, to := s.clock(t, w.from), s.clock(t, w.to); !t.Before(from) && t.Before(to) {
+			return to, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextWindowStart returns the start of the first business hours window after t.
+func (s *Schedule) nextWindowStart(t time.Time) (time.Time, bool) {
+	t = t.In(s.loc)
+	for day := 0; day <= 7; day++ {
+		date := time.Date(t.Year(), t.Month(), t.Day()+day, 0, 0, 0, 0, s.loc)
+		for _, w := range s.week[date.Weekday()] {
+			if from := s.clock(date, w.from); from.After(t) {
+				return from, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// holidayEnd returns the end of the holiday covering t, if any.
+func (s *Schedule) holidayEnd(t time.Time) (time.Time, bool) {
+	for _, h := range s.holidays {
+		for _, o := range h.occurrences(t) {
+			if !t.Before(o.Start) && t.Before(o.End) {
+				return o.End, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextHolidayStart returns the start of the first holiday after t.
+func (s *Schedule) nextHolidayStart(t time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, h := range s.holidays {
+		for _, o := range h.occurrences(t) {
+			if o.Start.After(t) && (next.IsZero() || o.Start.Before(next)) {
+				next = o.Start
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
@@ -0,0 +1,261 @@
+package hours
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var london, _ = time.LoadLocation("Europe/London")
+
+// calendar wraps the content lines of each event in a calendar, as exported files are laid out.
+func calendar(events ...string) string {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\n")
+	for _, event := range events {
+		b.WriteString("BEGIN:VEVENT\r\n" + event + "\r\nEND:VEVENT\r\n")
+	}
+	b.WriteString("END:VCALENDAR\r\n")
+	return b.String()
+}
+
+// christmas is an all-day event, to which the test cases add a recurrence rule.
+const christmas = "SUMMARY:Christmas\r\nDTSTART;VALUE=DATE:20261225\r\nDTEND;VALUE=DATE:20261226"
+
+func TestParseICS(t *testing.T) {
+	day := func(month time.Month, day int) time.Time { return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name    string
+		ics     string
+		want    []Holiday
+		wantErr bool
+	}{
+		{name: "all-day event", ics: calendar(christmas), want: []Holiday{{"Christmas", day(12, 25), day(12, 26), false}}},
+		{
+			name: "all-day event without end",
+			ics:  calendar("SUMMARY:Christmas\r\nDTSTART;VALUE=DATE:20261225"),
+			want: []Holiday{{"Christmas", day(12, 25), day(12, 26), false}},
+		},
+		{
+			name: "yearly",
+			ics:  calendar(christmas + "\r\nRRULE:FREQ=YEARLY"),
+			want: []Holiday{{"Christmas", day(12, 25), day(12, 26), true}},
+		},
+		{
+			name: "yearly spelled out",
+			ics:  calendar(christmas + "\r\nRRULE:FREQ=YEARLY;INTERVAL=1;BYMONTH=12;BYMONTHDAY=25;WKST=MO"),
+			want: []Holiday{{"Christmas", day(12, 25), day(12, 26), true}},
+		},
+		{
+			name: "yearly on another date",
+			ics:  calendar(christmas + "\r\nRRULE:FREQ=YEARLY;BYMONTH=1"),
+			want: []Holiday{{"Christmas", day(12, 25), day(12, 26), false}},
+		},
+		{
+			name: "every other year",
+			ics:  calendar(christmas + "\r\nRRULE:FREQ=YEARLY;INTERVAL=2"),
+			want: []Holiday{{"Christmas", day(12, 25), day(12, 26), false}},
+		},
+		{
+			name: "yearly a few times",
+			ics:  calendar(christmas + "\r\nRRULE:FREQ=YEARLY;COUNT=3"),
+			want: []Holiday{{"Christmas", day(12, 25), day(12, 26), false}},
+		},
+		{
+			name: "monthly",
+			ics:  calendar(christmas + "\r\nRRULE:FREQ=MONTHLY"),
+			want: []Holiday{{"Christmas", day(12, 25), day(12, 26), false}},
+		},
+		{name: "cancelled", ics: calendar(christmas + "\r\nSTATUS:CANCELLED")},
+		{name: "ends before it starts", ics: calendar("DTSTART;VALUE=DATE:20261225\r\nDTEND;VALUE=DATE:20261224")},
+		{
+			name: "UTC times",
+			ics:  calendar("SUMMARY:Outage\r\nDTSTART:20261020T090000Z\r\nDTEND:20261020T170000Z"),
+			want: []Holiday{{"Outage", day(10, 20).Add(9 * time.Hour), day(10, 20).Add(17 * time.Hour), false}},
+		},
+		{
+			name: "time zone",
+			ics: calendar(
+				"SUMMARY:Training\r\nDTSTART;TZID=Europe/London:20261020T090000\r\n" +
+					"DTEND;TZID=Europe/London:20261020T170000",
+			),
+			want: []Holiday{{
+				"Training",
+				time.Date(2026, 10, 20, 9, 0, 0, 0, london),
+				time.Date(2026, 10, 20, 17, 0, 0, 0, london),
+				false,
+			}},
+		},
+		{
+			name: "folded and escaped summary",
+			ics:  calendar("SUMMARY:Christmas\\, Boxing Day\r\n  and New Year\r\nDTSTART;VALUE=DATE:20261225"),
+			want: []Holiday{{"Christmas, Boxing Day and New Year", day(12, 25), day(12, 26), false}},
+		},
+		{name: "no start", ics: calendar("SUMMARY:Christmas"), wantErr: true},
+		{name: "invalid date", ics: calendar("DTSTART;VALUE=DATE:20261332"), wantErr: true},
+		{name: "malformed line", ics: calendar("SUMMARY"), wantErr: true},
+		{name: "end without begin", ics: "END:VEVENT\r\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseICS(strings.NewReader(tt.ics), time.UTC)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseICS() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseICS() = %+v, want %+v", got, tt.want)
+			}
+			for i, h := range got {
+				want := tt.want[i]
+				if h.Name != want.Name || !h.Start.Equal(want.Start) || !h.End.Equal(want.End) || h.Yearly != want.Yearly {
+					t.Errorf("holiday %d = %+v, want %+v", i, h, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []window
+		wantErr bool
+	}{
+		{name: "closed", spec: ""},
+		{name: "whole day", spec: "00:00-24:00", want: []window{{0, 24 * time.Hour}}},
+		{
+			name: "sorted",
+			spec: "13:00-17:00, 09:00-12:30",
+			want: []window{{9 * time.Hour, 12*time.Hour + 30*time.Minute}, {13 * time.Hour, 17 * time.Hour}},
+		},
+		{name: "no end", spec: "09:00", wantErr: true},
+		{name: "ends before it starts", spec: "17:00-09:00", wantErr: true},
+		{name: "overlapping", spec: "09:00-12:00, 11:00-13:00", wantErr: true},
+		{name: "past midnight", spec: "09:00-24:30", wantErr: true},
+		{name: "not a time", spec: "nine-five", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWindows(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWindows() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseWindows() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("parseWindows() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// testSchedule is open on weekdays from 09:00 to 17:00 in London with an hour's lunch, closed on Christmas 2026 and
+// on New Year's Day every year.
+func testSchedule(t *testing.T) *Schedule {
+	t.Helper()
+	weekday := "09:00-12:00, 13:00-17:00"
+	s, err := Config{
+		TimeZone:  "Europe/London",
+		Monday:    weekday,
+		Tuesday:   weekday,
+		Wednesday: weekday,
+		Thursday:  weekday,
+		Friday:    weekday,
+	}.Schedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.holidays = []Holiday{
+		{Name: "Christmas", Start: at(2026, 12, 25, 0), End: at(2026, 12, 26, 0)},
+		{Name: "New Year", Start: at(2026, 1, 1, 0), End: at(2026, 1, 2, 0), Yearly: true},
+	}
+	return s
+}
+
+// at returns the given hour of a date in London.
+func at(year int, month time.Month, day, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, london)
+}
+
+func TestScheduleAdd(t *testing.T) {
+	tests := []struct {
+		name  string
+		start time.Time
+		d     time.Duration
+		want  time.Time
+	}{
+		{name: "within a window", start: at(2026, 10, 19, 10), d: time.Hour, want: at(2026, 10, 19, 11)},
+		{name: "over lunch", start: at(2026, 10, 19, 11), d: 2 * time.Hour, want: at(2026, 10, 19, 14)},
+		{name: "before opening", start: at(2026, 10, 19, 7), d: time.Hour, want: at(2026, 10, 19, 10)},
+		{name: "after closing", start: at(2026, 10, 19, 20), d: time.Hour, want: at(2026, 10, 20, 10)},
+		{name: "over the weekend", start: at(2026, 10, 23, 16), d: 2 * time.Hour, want: at(2026, 10, 26, 10)},
+		{name: "a whole day", start: at(2026, 10, 23, 16), d: 8 * time.Hour, want: at(2026, 10, 26, 17)},
+		{name: "over a holiday", start: at(2026, 12, 24, 16), d: 2 * time.Hour, want: at(2026, 12, 28, 10)},
+		{name: "over a yearly holiday", start: at(2026, 12, 31, 16), d: 2 * time.Hour, want: at(2027, 1, 4, 10)},
+		{name: "nothing to add", start: at(2026, 10, 24, 12), d: 0, want: at(2026, 10, 26, 9)},
+	}
+	s := testSchedule(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Add(tt.start, tt.d); !got.Equal(tt.want) {
+				t.Errorf("Add() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{name: "opening", t: at(2026, 10, 19, 9), want: true},
+		{name: "lunch", t: at(2026, 10, 19, 12)},
+		{name: "closing", t: at(2026, 10, 19, 17)},
+		{name: "weekend", t: at(2026, 10, 24, 10)},
+		{name: "holiday", t: at(2026, 12, 25, 10)},
+		{name: "yearly holiday", t: at(2027, 1, 1, 10)},
+		{name: "holiday which does not recur", t: at(2027, 12, 24, 10), want: true},
+	}
+	s := testSchedule(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Open(tt.t); got != tt.want {
+				t.Errorf("Open() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNeverOpen(t *testing.T) {
+	s := testSchedule(t)
+	s.holidays = append(s.holidays, Holiday{Name: "Closed", Start: at(2026, 1, 1, 0), End: at(2030, 1, 1, 0)})
+	start := at(2026, 10, 19, 10)
+	if _, ok := s.NextOpen(start); ok {
+		t.Error("NextOpen() found an opening during a holiday of several years")
+	}
+	if got := s.Add(start, time.Hour); !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("Add() = %v, want %v", got, start.Add(time.Hour))
+	}
+	var always *Schedule
+	if !always.Open(start) || !always.Add(start, time.Hour).Equal(start.Add(time.Hour)) {
+		t.Error("a nil schedule is not always open")
+	}
+}
+
+func TestConfigSchedule(t *testing.T) {
+	if _, err := (Config{Monday: "09:00-17:00"}).Schedule(); err != nil {
+		t.Errorf("Schedule() error = %v", err)
+	}
+	if _, err := (Config{}).Schedule(); err == nil {
+		t.Error("Schedule() accepted business hours which never open")
+	}
+	if _, err := (Config{TimeZone: "Mars/Olympus_Mons", Monday: "09:00-17:00"}).Schedule(); err == nil {
+		t.Error("Schedule() accepted an unknown time zone")
+	}
+}
@@ -0,0 +1,190 @@
+package hours
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ParseICS reads the events of an iCalendar file as holidays. All-day events cover whole days in loc, as do times
+// without a time zone; events recurring every year, as public holidays often do, are kept as yearly holidays. Other
+// recurrence rules are not supported and only their first occurrence is kept. Cancelled events are skipped.
+func ParseICS(r io.Reader, loc *time.Location) ([]Holiday, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		holidays []Holiday
+		event    map[string]icsProperty
+	)
+	for i, line := range lines {
+		p, ok := parseICSProperty(line)
+		if !ok {
+			return nil, errors.Errorf("line %d: malformed property", i+1)
+		}
+		switch {
+		case p.name == "BEGIN" && p.value == "VEVENT":
+			event = make(map[string]icsProperty)
+		case p.name == "END" && p.value == "VEVENT":
+			if event == nil {
+				return nil, errors.Errorf("line %d: END:VEVENT without BEGIN:VEVENT", i+1)
+			}
+			h, ok, err := icsHoliday(event, loc)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "event ending on line %d", i+1)
+			}
+			if ok {
+				holidays = append(holidays, h)
+			}
+			event = nil
+		case event != nil:
+			event[p.name] = p
+		}
+	}
+	return holidays, nil
+}
+
+// unfoldICS splits the file into its logical lines, joining lines continued with leading whitespace.
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithMessage(err, "failed to read calendar")
+	}
+	return lines, nil
+}
+
+// icsProperty is a content line such as "DTSTART;VALUE=DATE:20261225".
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func parseICSProperty(line string) (icsProperty, bool) {
+	head, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return icsProperty{}, false
+	}
+	parts := strings.Split(head, ";")
+	p := icsProperty{name: strings.ToUpper(parts[0]), params: make(map[string]string), value: value}
+	for _, param := range parts[1:] {
+		k, v, _ := strings.Cut(param, "=")
+		p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return p, true
+}
+
+// icsHoliday turns an event's properties into a holiday. It reports false for events which close nothing, such as
+// cancelled ones.
+func icsHoliday(event map[string]icsProperty, loc *time.Location) (Holiday, bool, error) {
+	if strings.EqualFold(event["STATUS"].value, "CANCELLED") {
+		return Holiday{}, false, nil
+	}
+	startProp, ok := event["DTSTART"]
+	if !ok {
+		return Holiday{}, false, errors.New("event has no DTSTART")
+	}
+	start, allDay, err := parseICSTime(startProp, loc)
+	if err != nil {
+		return Holiday{}, false, err
+	}
+	h := Holiday{Name: unescapeICS(event["SUMMARY"].value), Start: start}
+	if endProp, ok := event["DTEND"]; ok {
+		if h.End, _, err = parseICSTime(endProp, loc); err != nil {
+			return Holiday{}, false, err
+		}
+	} else if allDay {
+		// an all-day event without an end lasts the one day
+		h.End = start.AddDate(0, 0, 1)
+	}
+	if !h.End.After(h.Start) {
+		return Holiday{}, false, nil
+	}
+	if rule, ok := event["RRULE"]; ok {
+		if yearlyRule(rule.value, start) {
+			h.Yearly = true
+		} else {
+			slog.Warn(
+				"Unsupported recurrence in holiday calendar, only the first occurrence is used",
+				slog.String("holiday", h.Name),
+				slog.String("rule", rule.value),
+			)
+		}
+	}
+	return h, true, nil
+}
+
+// yearlyRule reports whether the recurrence rule repeats the event on the same date every year from start on, as
+// "FREQ=YEARLY" does and calendar exports often spell out as "FREQ=YEARLY;INTERVAL=1;BYMONTH=12;BYMONTHDAY=25".
+// Rules with any other part, such as a COUNT, an UNTIL or a weekday, are not yearly by this measure.
+func yearlyRule(rule string, start time.Time) bool {
+	yearly := false
+	for _, part := range strings.Split(rule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			yearly = strings.EqualFold(value, "YEARLY")
+		case "INTERVAL":
+			if value != "1" {
+				return false
+			}
+		case "BYMONTH":
+			if value != strconv.Itoa(int(start.Month())) {
+				return false
+			}
+		case "BYMONTHDAY":
+			if value != strconv.Itoa(start.Day()) {
+				return false
+			}
+		case "WKST":
+			// the start of the week makes no difference to a rule without weeks
+		default:
+			return false
+		}
+	}
+	return yearly
+}
+
+// parseICSTime parses a DATE or DATE-TIME value, reporting whether it was a date. Times in UTC end in "Z", times
+// with a TZID parameter are in that zone and any other time is in loc.
+func parseICSTime(p icsProperty, loc *time.Location) (time.Time, bool, error) {
+	if len(p.value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", p.value, loc)
+		return t, true, errors.WithMessagef(err, "invalid date %q", p.value)
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		t, err := time.Parse("20060102T150405Z", p.value)
+		return t, false, errors.WithMessagef(err, "invalid time %q", p.value)
+	}
+	zone := loc
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			zone = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", p.value, zone)
+	return t, false, errors.WithMessagef(err, "invalid time %q", p.value)
+}
+
+var icsUnescaper = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`)
+
+func unescapeICS(s string) string {
+	return icsUnescaper.Replace(s)
+}
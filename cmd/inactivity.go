@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/store"
+	"github.com/kapparina/ticketsplease/cmd/templates"
+)
+
+// InactiveCloseReason is recorded for tickets closed because their opener stopped replying.
+const InactiveCloseReason = "inactive"
+
+// Inactivity steps, in the order they are taken. They are stored as the ticket's inactivity stage.
+const (
+	InactivityReminder = iota + 1
+	InactivityWarning
+	InactivityClose
+)
+
+var (
+	ErrInactivityPaused    = errors.New("ticket's inactivity reminders are already paused")
+	ErrInactivityNotPaused = errors.New("ticket's inactivity reminders are not paused")
+)
+
+// CheckInactivity takes the inactivity step due for each open ticket, if any. Failures are logged per ticket so
+// that one broken ticket does not hold up the others.
+func CheckInactivity(ctx context.Context, b *Bot, now time.Time) {
+	tickets, err := b.Store.ListTickets(ctx, store.TicketFilter{Status: store.TicketStatusOpen})
+	if err != nil {
+		slog.Error("Failed to list open tickets", slog.Any("err", err))
+		return
+	}
+	for i := range tickets {
+		t := &tickets[i]
+		if b.Client.Caches().IsGuildUnavailable(t.GuildID) {
+			continue
+		}
+		p := b.Cfg.InactivityPolicy(t.GuildID, t.Category)
+		step := NextInactivityStep(p, t, now)
+		if step == 0 {
+			continue
+		}
+		if err = takeInactivityStep(ctx, b, t, p, step, now); err != nil {
+			slog.Error(
+				"Failed to take inactivity step",
+				slog.Int64("ticket_id", t.ID),
+				slog.Int("step", step),
+				slog.Any("err", err),
+			)
+		}
+	}
+}
+
+// NextInactivityStep returns the inactivity step due for the ticket at the given time, or 0 if none is. Only
+// tickets whose opener has yet to answer the support team's last message are considered, and steps are taken in
+// order without skipping any, each leaving the opener as long as the policy does since the one before, so that
+// tickets are never closed without the configured reminders, even after the bot was offline.
+func NextInactivityStep(p common.InactivityPolicy, t *store.Ticket, now time.Time) int {
+	if t.InactivityPaused || !p.Enabled() || !awaitingOpener(t) {
+		return 0
+	}
+	thresholds := p.Thresholds()
+	var since time.Duration
+	if t.InactivityStage > 0 {
+		since = thresholds[t.InactivityStage-1]
+	}
+	for step := t.InactivityStage + 1; step <= InactivityClose; step++ {
+		threshold := thresholds[step-1]
+		if threshold == 0 {
+			continue
+		}
+		if now.Sub(*t.LastStaffActivityAt) < threshold {
+			return 0
+		}
+		if t.InactivityStageAt != nil && now.Sub(*t.InactivityStageAt) < threshold-since {
+			return 0
+		}
+		return step
+	}
+	return 0
+}
+
+// awaitingOpener reports whether the support team spoke last in the ticket.
+func awaitingOpener(t *store.Ticket) bool {
+	if t.LastStaffActivityAt == nil {
+		return false
+	}
+	return t.LastUserActivityAt == nil || !t.LastUserActivityAt.After(*t.LastStaffActivityAt)
+}
+
+func takeInactivityStep(
+	ctx context.Context, b *Bot, t *store.Ticket, p common.InactivityPolicy, step int, now time.Time,
+) error {
+	if step == InactivityClose {
+		return CloseTicket(ctx, b, t, 0, InactiveCloseReason)
+	}
+	thresholds := p.Thresholds()
+	data := templates.TicketInactiveData{
+		Number:   t.Number,
+		OpenerID: t.OpenerID.String(),
+		Final:    step == InactivityWarning,
+	}
+	if closeAfter := thresholds[InactivityClose-1]; closeAfter > 0 {
+		data.ClosesAt = now.Add(closeAfter - thresholds[step-1]).Unix()
+	}
+	content, err := templates.PopulateTicketInactiveData(data)
+	if err != nil {
+		return errors.WithMessage(err, "failed to populate inactivity reminder")
+	}
+	if _, err = b.Client.Rest().CreateMessage(
+		t.ThreadID,
+		discord.NewMessageCreateBuilder().
+			SetContent(content).
+			SetAllowedMentions(&discord.AllowedMentions{Users: []snowflake.ID{t.OpenerID}}).
+			Build(),
+	); err != nil {
+		return errors.WithMessage(err, "failed to send inactivity reminder")
+	}
+	if err = b.Store.SetTicketInactivity(ctx, t.ID, step, now); err != nil {
+		return err
+	}
+	detail := "reminder"
+	if data.Final {
+		detail = "final warning"
+	}
+	RecordTicketEvent(ctx, b, store.TicketEvent{
+		TicketID: t.ID,
+		Kind:     store.TicketEventReminded,
+		Detail:   detail,
+	})
+	return nil
+}
+
+// PauseInactivity stops the ticket's inactivity reminders and automatic closure until they are resumed, for instance
+// while the support team awaits information from elsewhere.
+func PauseInactivity(ctx context.Context, b *Bot, t *store.Ticket, actorID snowflake.ID, reason string) error {
+	if !t.IsOpen() {
+		return ErrTicketNotOpen
+	}
+	if t.InactivityPaused {
+		return ErrInactivityPaused
+	}
+	if err := b.Store.SetTicketInactivityPaused(ctx, t.ID, true); err != nil {
+		return err
+	}
+	t.InactivityPaused = true
+	RecordTicketEvent(ctx, b, store.TicketEvent{
+		TicketID: t.ID,
+		Kind:     store.TicketEventPaused,
+		ActorID:  actorID,
+		Detail:   reason,
+	})
+	content := "Inactivity reminders are paused for this ticket."
+	if reason != "" {
+		content = "Inactivity reminders are paused for this ticket: " + reason
+	}
+	return postTicketNotice(b, t, content)
+}
+
+// ResumeInactivity resumes the ticket's inactivity reminders and automatic closure. The inactivity clock restarts,
+// so that the opener is not reminded straight away for the time the ticket was paused.
+func ResumeInactivity(ctx context.Context, b *Bot, t *store.Ticket, actorID snowflake.ID) error {
+	if !t.IsOpen() {
+		return ErrTicketNotOpen
+	}
+	if !t.InactivityPaused {
+		return ErrInactivityNotPaused
+	}
+	if err := b.Store.SetTicketInactivityPaused(ctx, t.ID, false); err != nil {
+		return err
+	}
+	t.InactivityPaused = false
+	restartInactivity(ctx, b, t)
+	RecordTicketEvent(ctx, b, store.TicketEvent{
+		TicketID: t.ID,
+		Kind:     store.TicketEventResumed,
+		ActorID:  actorID,
+	})
+	return postTicketNotice(b, t, "Inactivity reminders are resumed for this ticket.")
+}
+
+// restartInactivity starts the ticket's inactivity clock afresh. Failures are logged rather than returned, as the
+// worst outcome is an early reminder.
+func restartInactivity(ctx context.Context, b *Bot, t *store.Ticket) {
+	if err := b.Store.SetTicketInactivity(ctx, t.ID, 0, time.Now().UTC()); err != nil {
+		slog.Error("Failed to restart ticket inactivity", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+	}
+}
+
+// postTicketNotice posts a short notice to the ticket's thread without pinging anyone.
+func postTicketNotice(b *Bot, t *store.Ticket, content string) error {
+	if _, err := b.Client.Rest().CreateMessage(
+		t.ThreadID,
+		discord.NewMessageCreateBuilder().
+			SetContent(content).
+			SetAllowedMentions(&discord.AllowedMentions{}).
+			Build(),
+	); err != nil {
+		return errors.WithMessage(err, "failed to post ticket notice")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+)
+
+// InteractionTimeout bounds the work done for a deferred interaction. Discord accepts the reply for 15 minutes,
+// but nobody waits that long for a ticket to be created.
+const InteractionTimeout = 30 * time.Second
+
+// DeferrableEvent is an interaction which can be answered later; the command, component and modal events of the
+// handler package all are.
+type DeferrableEvent interface {
+	DeferCreateMessage(ephemeral bool, opts ...rest.RequestOpt) error
+	Client() bot.Client
+	ApplicationID() snowflake.ID
+	Token() string
+}
+
+// Reply builds the reply of a deferred interaction from a message.
+func Reply(content string) discord.MessageUpdate {
+	return discord.NewMessageUpdateBuilder().SetContent(content).Build()
+}
+
+// Replyf builds the reply of a deferred interaction from a formatted message.
+func Replyf(format string, a ...any) discord.MessageUpdate {
+	return Reply(fmt.Sprintf(format, a...))
+}
+
+// DeferReply acknowledges the interaction with an ephemeral "thinking" response straight away, so that slow REST
+// calls cannot run into Discord's three second deadline, then runs work with a context bounded by
+// InteractionTimeout and replaces the response with the reply work returns. Ticket errors are answered with their
+// message; any other error is returned for ErrorMiddleware to answer.
+func DeferReply(
+	ctx context.Context, e DeferrableEvent, work func(ctx context.Context) (discord.MessageUpdate, error),
+) error {
+	if err := e.DeferCreateMessage(true); err != nil {
+		return errors.WithMessage(err, "failed to defer interaction response")
+	}
+	ctx, cancel := context.WithTimeout(ctx, InteractionTimeout)
+	defer cancel()
+	reply, err := work(ctx)
+	if msg, ok := TicketErrorMessage(err); ok {
+		reply = Reply(msg)
+	} else if err != nil {
+		return deferredError{err}
+	}
+	if _, err = e.Client().Rest().UpdateInteractionResponse(e.ApplicationID(), e.Token(), reply); err != nil {
+		return deferredError{errors.WithMessage(err, "failed to send deferred reply")}
+	}
+	return nil
+}
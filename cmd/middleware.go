@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/handler"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+)
+
+// DefaultErrorMessage is the reply members get when an interaction fails, unless the configuration localises it.
+// "{id}" is replaced by the failure's correlation ID.
+const DefaultErrorMessage = "Something went wrong while handling that; please try again later. " +
+	"If it keeps happening, let the server's staff know the error ID `{id}`."
+
+// maxDiagnosticErrorLength keeps diagnostics posts short; the full error is in the log under the correlation ID.
+const maxDiagnosticErrorLength = 300
+
+// deferredError marks a failure of an interaction which has already been deferred, so that it is answered by
+// editing the deferred response rather than by responding.
+type deferredError struct {
+	error
+}
+
+func (e deferredError) Unwrap() error {
+	return e.error
+}
+
+// panicError is a handler's panic. Its stack is only logged, never posted to the diagnostics channel.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// ErrorMiddleware answers every interaction whose handler returns an error or panics: the failure is logged with
+// its context under a short correlation ID, the member gets an ephemeral apology naming that ID, and a summary
+// is posted to the guild's diagnostics channel, if any. Autocomplete interactions get an empty list of choices.
+func ErrorMiddleware(b *Bot) handler.Middleware {
+	return func(next handler.Handler) handler.Handler {
+		return func(e *handler.InteractionEvent) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = panicError{value: r, stack: debug.Stack()}
+				}
+				if err != nil {
+					reportInteractionError(b, e, err)
+					err = nil
+				}
+			}()
+			return next(e)
+		}
+	}
+}
+
+// reportInteractionError logs a failed interaction, answers the member and posts a summary of the failure to the
+// diagnostics channel.
+func reportInteractionError(b *Bot, e *handler.InteractionEvent, err error) {
+	id := newCorrelationID()
+	var guildID snowflake.ID
+	if e.GuildID() != nil {
+		guildID = *e.GuildID()
+	}
+	path := interactionPath(e.Interaction)
+	attrs := []any{
+		slog.String("correlation_id", id),
+		slog.String("path", path),
+		slog.Any("guild_id", guildID),
+		slog.Any("channel_id", e.Channel().ID()),
+		slog.Any("user_id", e.User().ID),
+		slog.Any("err", err),
+	}
+	var p panicError
+	if errors.As(err, &p) {
+		attrs = append(attrs, slog.String("stack", string(p.stack)))
+	}
+	slog.Error("Failed to handle interaction", attrs...)
+	if replyErr := replyInteractionError(b, e, err, id); replyErr != nil {
+		slog.Error("Failed to reply to failed interaction", slog.String("correlation_id", id), slog.Any("err", replyErr))
+	}
+	channelID := b.Cfg.DiagnosticsChannel(guildID)
+	if channelID == 0 {
+		return
+	}
+	if _, postErr := b.Client.Rest().CreateMessage(
+		channelID,
+		discord.NewMessageCreateBuilder().
+			SetContentf(
+				"Error `%s` in `%s` used by <@%s> in <#%s>:\n```\n%s\n```",
+				id, path, e.User().ID, e.Channel().ID(), strings.ReplaceAll(errorSummary(err), "```", "'''"),
+			).
+			SetAllowedMentions(&discord.AllowedMentions{}).
+			Build(),
+	); postErr != nil {
+		slog.Error("Failed to post diagnostics", slog.String("correlation_id", id), slog.Any("err", postErr))
+	}
+}
+
+// errorSummary returns the first line of the error, cut short on a character boundary if it is too long.
+func errorSummary(err error) string {
+	summary, _, cut := strings.Cut(err.Error(), "\n")
+	if runes := []rune(summary); len(runes) > maxDiagnosticErrorLength {
+		summary, cut = string(runes[:maxDiagnosticErrorLength]), true
+	}
+	if cut {
+		summary += "…"
+	}
+	return summary
+}
+
+// replyInteractionError answers the member whose interaction failed. An interaction which was already answered,
+// such as one which failed after being deferred, gets its response replaced instead.
+func replyInteractionError(b *Bot, e *handler.InteractionEvent, err error, id string) error {
+	if _, ok := e.Interaction.(discord.AutocompleteInteraction); ok {
+		return e.AutocompleteResult(nil)
+	}
+	msg := strings.ReplaceAll(ErrorMessage(b, e.Locale()), "{id}", id)
+	if !errors.As(err, &deferredError{}) {
+		createErr := e.CreateMessage(discord.NewMessageCreateBuilder().SetContent(msg).SetEphemeral(true).Build())
+		if createErr == nil {
+			return nil
+		}
+		slog.Debug("Failed to respond to failed interaction, replacing its response", slog.Any("err", createErr))
+	}
+	_, err = e.Client().Rest().UpdateInteractionResponse(e.ApplicationID(), e.Token(), Reply(msg))
+	return err
+}
+
+// ErrorMessage returns the reply for a failed interaction in the given locale, falling back to the language
+// without its region, to the configured default and finally to DefaultErrorMessage.
+func ErrorMessage(b *Bot, locale discord.Locale) string {
+	messages := b.Cfg.Errors.Messages
+	language, _, _ := strings.Cut(locale.Code(), "-")
+	for _, key := range []string{locale.Code(), language, "default"} {
+		if msg, ok := messages[key]; ok {
+			return msg
+		}
+	}
+	return DefaultErrorMessage
+}
+
+// interactionPath describes what the interaction was: the command's path or the component's custom ID.
+func interactionPath(i discord.Interaction) string {
+	switch i := i.(type) {
+	case discord.ApplicationCommandInteraction:
+		if data, ok := i.Data.(discord.SlashCommandInteractionData); ok {
+			return data.CommandPath()
+		}
+		return "/" + i.Data.CommandName()
+	case discord.AutocompleteInteraction:
+		return i.Data.CommandPath()
+	case discord.ComponentInteraction:
+		return i.Data.CustomID()
+	case discord.ModalSubmitInteraction:
+		return i.Data.CustomID
+	default:
+		return fmt.Sprintf("interaction type %d", i.Type())
+	}
+}
+
+// newCorrelationID returns a short random ID which ties a member's report of a failure to its log entry.
+func newCorrelationID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
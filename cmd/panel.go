@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/commands"
+	"github.com/kapparina/ticketsplease/cmd/templates"
+)
+
+// Custom IDs of the ticket panel's components. They name no guild or message, so the panel posted before a restart
+// keeps working afterwards.
+const (
+	PanelCategoryID = "/ticket-panel/category"
+	PanelHelpID     = "/ticket-panel/help"
+)
+
+// maxSelectMenuOptions is Discord's limit on the options of a select menu.
+const maxSelectMenuOptions = 25
+
+// SupportPanel builds the ticket panel posted in the guild's support channel: the help message, a menu of the
+// categories tickets can be opened in and a help button. Only the first 25 enabled categories fit into the menu;
+// any others can still be picked through the ticket creation command.
+func SupportPanel(b *Bot, guildID snowflake.ID) (string, []discord.ContainerComponent, error) {
+	content, err := templates.PopulateHelpData(templates.HelpData{
+		CommandName: commands.TicketCreateCommandName,
+		Version:     b.GitTag,
+	})
+	if err != nil {
+		return "", nil, errors.WithMessage(err, "failed to populate help message")
+	}
+	categories := EnabledCategories(b, guildID)
+	if len(categories) > maxSelectMenuOptions {
+		slog.Warn(
+			"Too many categories for the ticket panel, only the first ones are listed",
+			slog.Any("guild_id", guildID),
+			slog.Int("categories", len(categories)),
+		)
+		categories = categories[:maxSelectMenuOptions]
+	}
+	var rows []discord.ContainerComponent
+	if len(categories) > 0 {
+		options := make([]discord.StringSelectMenuOption, 0, len(categories))
+		for _, c := range categories {
+			options = append(options, discord.NewStringSelectMenuOption(c.Label(), c.Title))
+		}
+		rows = append(rows, discord.NewActionRow(
+			discord.NewStringSelectMenu(PanelCategoryID, "Open a ticket…", options...),
+		))
+	}
+	rows = append(rows, discord.NewActionRow(discord.NewSecondaryButton("How does this work?", PanelHelpID)))
+	return content, rows, nil
+}
+
+// panelMatches reports whether the message already shows the given panel.
+func panelMatches(m discord.Message, content string, rows []discord.ContainerComponent) bool {
+	return m.Content == content && componentsSignature(m.Components) == componentsSignature(rows)
+}
+
+// componentsSignature summarises the parts of the components the panel sets, so that a posted panel can be compared
+// with the desired one regardless of the fields Discord fills in.
+func componentsSignature(rows []discord.ContainerComponent) string {
+	var sb strings.Builder
+	for _, row := range rows {
+		r, ok := row.(discord.ActionRowComponent)
+		if !ok {
+			continue
+		}
+		sb.WriteString("|")
+		for _, c := range r.Components() {
+			switch c := c.(type) {
+			case discord.StringSelectMenuComponent:
+				sb.WriteString(c.CustomID + ":" + c.Placeholder)
+				for _, o := range c.Options {
+					sb.WriteString(";" + o.Value + "=" + o.Label)
+				}
+			case discord.ButtonComponent:
+				sb.WriteString(c.CustomID + ":" + c.Label)
+			}
+			sb.WriteString(",")
+		}
+	}
+	return sb.String()
+}
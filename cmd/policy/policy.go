@@ -0,0 +1,253 @@
+package policy
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+)
+
+// permissionNames maps the permission names accepted in the configuration to their bits.
+var permissionNames = map[string]discord.Permissions{
+	"administrator":       discord.PermissionAdministrator,
+	"view_audit_log":      discord.PermissionViewAuditLog,
+	"manage_guild":        discord.PermissionManageGuild,
+	"manage_roles":        discord.PermissionManageRoles,
+	"manage_channels":     discord.PermissionManageChannels,
+	"manage_messages":     discord.PermissionManageMessages,
+	"manage_threads":      discord.PermissionManageThreads,
+	"manage_nicknames":    discord.PermissionManageNicknames,
+	"kick_members":        discord.PermissionKickMembers,
+	"ban_members":         discord.PermissionBanMembers,
+	"moderate_members":    discord.PermissionModerateMembers,
+	"mention_everyone":    discord.PermissionMentionEveryone,
+	"view_guild_insights": discord.PermissionViewGuildInsights,
+}
+
+// Tier defines which roles make up an access tier. A role belongs to the tier if it has every one of Permissions,
+// is named one of RoleNames or is one of RoleIDs. A tier without any rules has no roles.
+type Tier struct {
+	Permissions []string       `toml:"permissions"`
+	RoleNames   []string       `toml:"role_names"`
+	RoleIDs     []snowflake.ID `toml:"role_ids"`
+}
+
+// matches reports whether the role belongs to the tier.
+func (t Tier) matches(r discord.Role) bool {
+	if len(t.Permissions) > 0 && r.Permissions.Has(t.permissions()...) {
+		return true
+	}
+	return slices.Contains(t.RoleNames, r.Name) || slices.Contains(t.RoleIDs, r.ID)
+}
+
+func (t Tier) empty() bool {
+	return len(t.Permissions) == 0 && len(t.RoleNames) == 0 && len(t.RoleIDs) == 0
+}
+
+func (t Tier) permissions() []discord.Permissions {
+	perms := make([]discord.Permissions, 0, len(t.Permissions))
+	for _, name := range t.Permissions {
+		perms = append(perms, permissionNames[name])
+	}
+	return perms
+}
+
+// Policy maps each access level to the tier of roles holding it. Higher tiers inherit every ticket of the lower
+// ones, so a moderator-level ticket is also handled by staff, admins and the owner. The guild owner always belongs
+// to the owner tier, whatever roles they hold.
+type Policy struct {
+	Moderator Tier `toml:"moderator"`
+	Staff     Tier `toml:"staff"`
+	Admin     Tier `toml:"admin"`
+	Owner     Tier `toml:"owner"`
+}
+
+// Default is the policy the configuration is applied on top of: moderators can view the audit log and manage
+// messages, admins have the administrator permission, and the staff and owner tiers have no roles.
+var Default = Policy{
+	Moderator: Tier{Permissions: []string{"view_audit_log", "manage_messages"}},
+	Admin:     Tier{Permissions: []string{"administrator"}},
+}
+
+// Tier returns the tier holding the given access level.
+func (p Policy) Tier(level common.AccessLevel) Tier {
+	switch level {
+	case common.AccessModerator:
+		return p.Moderator
+	case common.AccessStaff:
+		return p.Staff
+	case common.AccessAdmin:
+		return p.Admin
+	case common.AccessOwner:
+		return p.Owner
+	default:
+		return Tier{}
+	}
+}
+
+// Override returns the policy with every tier which o defines replacing the corresponding tier.
+func (p Policy) Override(o Policy) Policy {
+	for _, t := range []struct{ dst, src *Tier }{
+		{&p.Moderator, &o.Moderator}, {&p.Staff, &o.Staff}, {&p.Admin, &o.Admin}, {&p.Owner, &o.Owner},
+	} {
+		if !t.src.empty() {
+			*t.dst = *t.src
+		}
+	}
+	return p
+}
+
+// Validate checks that every tier only names known permissions.
+func (p Policy) Validate() error {
+	for _, level := range common.AccessLevels {
+		for _, name := range p.Tier(level).Permissions {
+			if _, ok := permissionNames[name]; !ok {
+				return errors.Errorf("%s tier: unknown permission %q", level, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Guild is the state of a guild the policy is evaluated against.
+type Guild struct {
+	ID      snowflake.ID
+	OwnerID snowflake.ID
+	Roles   []discord.Role
+}
+
+// Audience is everyone responsible for a ticket: the roles to ping, and the members to add to its thread
+// individually because no role covers them.
+type Audience struct {
+	Roles   []discord.Role
+	UserIDs []snowflake.ID
+}
+
+// RoleIDs returns the sorted IDs of the audience's roles.
+func (a Audience) RoleIDs() []snowflake.ID {
+	ids := make([]snowflake.ID, len(a.Roles))
+	for i, r := range a.Roles {
+		ids[i] = r.ID
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Resolve returns who is responsible for tickets of the given access level in the guild: every unmanaged role in
+// the level's tier or above, plus the extra roles given, such as a category's ping roles. The guild owner is
+// included individually for owner-level tickets only, so that they are not pinged for everything.
+func (p Policy) Resolve(level common.AccessLevel, g Guild, extraRoleIDs ...snowflake.ID) Audience {
+	tiers := p.tiersFrom(level)
+	var a Audience
+	for _, r := range g.Roles {
+		if r.Managed || r.ID == g.ID {
+			continue
+		}
+		if slices.Contains(extraRoleIDs, r.ID) || slices.ContainsFunc(tiers, func(t Tier) bool { return t.matches(r) }) {
+			a.Roles = append(a.Roles, r)
+		}
+	}
+	if level == common.AccessOwner && g.OwnerID != 0 {
+		a.UserIDs = append(a.UserIDs, g.OwnerID)
+	}
+	return a
+}
+
+// Allows reports whether the member may manage tickets of the given access level in the guild, which is the case
+// for the guild owner and for holders of any role in the level's audience.
+func (p Policy) Allows(level common.AccessLevel, g Guild, member discord.Member, extraRoleIDs ...snowflake.ID) bool {
+	if member.User.ID != 0 && member.User.ID == g.OwnerID {
+		return true
+	}
+	for _, r := range p.Resolve(level, g, extraRoleIDs...).Roles {
+		if slices.Contains(member.RoleIDs, r.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lead returns who is first in line for tickets of the given access level: the roles of the lowest tier from the
+// level up which has any in the guild, plus the extra roles given. The guild owner is included for owner-level
+// tickets, as with Resolve.
+func (p Policy) Lead(level common.AccessLevel, g Guild, extraRoleIDs ...snowflake.ID) Audience {
+	var a Audience
+	if lead, ok := p.leadLevel(level, g); ok {
+		a.Roles = p.tierRoles(lead, g)
+	}
+	for _, r := range g.Roles {
+		lead := slices.ContainsFunc(a.Roles, func(o discord.Role) bool { return o.ID == r.ID })
+		if !lead && slices.Contains(extraRoleIDs, r.ID) {
+			a.Roles = append(a.Roles, r)
+		}
+	}
+	if level == common.AccessOwner && g.OwnerID != 0 {
+		a.UserIDs = append(a.UserIDs, g.OwnerID)
+	}
+	return a
+}
+
+// Escalate returns who tickets of the given access level are escalated to: the roles of the lowest tier with any in
+// the guild above the one Lead picks, so that escalations never ping those first in line again. Owner-level tickets,
+// and tickets with no such tier, are escalated to the guild owner.
+func (p Policy) Escalate(level common.AccessLevel, g Guild) Audience {
+	above := level
+	if lead, ok := p.leadLevel(level, g); ok {
+		above = lead
+	}
+	for _, l := range levelsFrom(above)[1:] {
+		if roles := p.tierRoles(l, g); len(roles) > 0 {
+			return Audience{Roles: roles}
+		}
+	}
+	if g.OwnerID != 0 {
+		return Audience{UserIDs: []snowflake.ID{g.OwnerID}}
+	}
+	return Audience{}
+}
+
+// leadLevel returns the lowest access level from the given one up whose tier has any roles in the guild.
+func (p Policy) leadLevel(level common.AccessLevel, g Guild) (common.AccessLevel, bool) {
+	for _, l := range levelsFrom(level) {
+		if len(p.tierRoles(l, g)) > 0 {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// tierRoles returns the guild's unmanaged roles in the tier holding the given access level.
+func (p Policy) tierRoles(level common.AccessLevel, g Guild) []discord.Role {
+	tier := p.Tier(level)
+	var roles []discord.Role
+	for _, r := range g.Roles {
+		if !r.Managed && r.ID != g.ID && tier.matches(r) {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// tiersFrom returns the tier holding the given access level and every tier above it.
+func (p Policy) tiersFrom(level common.AccessLevel) []Tier {
+	levels := levelsFrom(level)
+	tiers := make([]Tier, 0, len(levels))
+	for _, l := range levels {
+		tiers = append(tiers, p.Tier(l))
+	}
+	return tiers
+}
+
+// levelsFrom returns the given access level and every level above it. Unknown levels are treated as the lowest
+// level.
+func levelsFrom(level common.AccessLevel) []common.AccessLevel {
+	i := slices.Index(common.AccessLevels, level)
+	if i < 0 {
+		i = 0
+	}
+	return common.AccessLevels[i:]
+}
@@ -0,0 +1,237 @@
+package policy
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+)
+
+const (
+	guildID   snowflake.ID = 1
+	ownerID   snowflake.ID = 2
+	modID     snowflake.ID = 10
+	helperID  snowflake.ID = 11
+	adminID   snowflake.ID = 12
+	founderID snowflake.ID = 13
+	botID     snowflake.ID = 14
+	memberID  snowflake.ID = 15
+)
+
+// ids shortens the role and user IDs of the test tables
+type ids = []snowflake.ID
+
+var modPermissions = discord.PermissionViewAuditLog | discord.PermissionManageMessages
+
+// testGuild has a role for each tier of testPolicy, a managed bot role and an @everyone role which would both match
+// every tier by permissions.
+var testGuild = Guild{
+	ID:      guildID,
+	OwnerID: ownerID,
+	Roles: []discord.Role{
+		{ID: guildID, Name: "@everyone", Permissions: discord.PermissionsAll},
+		{ID: modID, Name: "Moderator", Permissions: modPermissions},
+		{ID: helperID, Name: "Helper"},
+		{ID: adminID, Name: "Admin", Permissions: discord.PermissionAdministrator},
+		{ID: founderID, Name: "Founder"},
+		{ID: botID, Name: "Bot", Permissions: discord.PermissionsAll, Managed: true},
+		{ID: memberID, Name: "Member", Permissions: discord.PermissionViewAuditLog},
+	},
+}
+
+// testPolicy defines each tier a different way: moderators by permissions, staff by role name, admins by
+// permission and the owner tier by role ID.
+var testPolicy = Default.Override(Policy{
+	Staff: Tier{RoleNames: []string{"Helper"}},
+	Owner: Tier{RoleIDs: ids{founderID}},
+})
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   common.AccessLevel
+		extra   ids
+		roles   ids
+		userIDs ids
+	}{
+		{
+			name:  "moderator inherits every tier",
+			level: common.AccessModerator,
+			roles: ids{modID, helperID, adminID, founderID},
+		},
+		{name: "staff excludes moderators", level: common.AccessStaff, roles: ids{helperID, adminID, founderID}},
+		{name: "admin", level: common.AccessAdmin, roles: ids{adminID, founderID}},
+		{name: "owner includes the guild owner", level: common.AccessOwner, roles: ids{founderID}, userIDs: ids{ownerID}},
+		{name: "extra roles", level: common.AccessAdmin, extra: ids{memberID}, roles: ids{adminID, founderID, memberID}},
+		{name: "unknown level is the lowest", level: "janitor", roles: ids{modID, helperID, adminID, founderID}},
+		{
+			name:    "extra managed role is excluded",
+			level:   common.AccessOwner,
+			extra:   ids{botID},
+			roles:   ids{founderID},
+			userIDs: ids{ownerID},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := testPolicy.Resolve(tt.level, testGuild, tt.extra...)
+			if got := a.RoleIDs(); !slices.Equal(got, tt.roles) {
+				t.Errorf("roles = %v, want %v", got, tt.roles)
+			}
+			if !slices.Equal(a.UserIDs, tt.userIDs) {
+				t.Errorf("user IDs = %v, want %v", a.UserIDs, tt.userIDs)
+			}
+		})
+	}
+}
+
+func TestTierMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		tier Tier
+		role discord.Role
+		want bool
+	}{
+		{name: "every permission", tier: Default.Moderator, role: discord.Role{Permissions: modPermissions}, want: true},
+		{name: "some permissions", tier: Default.Moderator, role: discord.Role{Permissions: discord.PermissionViewAuditLog}},
+		{name: "role name", tier: Tier{RoleNames: []string{"Helper"}}, role: discord.Role{Name: "Helper"}, want: true},
+		{name: "role name is case sensitive", tier: Tier{RoleNames: []string{"Helper"}}, role: discord.Role{Name: "helper"}},
+		{name: "role ID", tier: Tier{RoleIDs: ids{founderID}}, role: discord.Role{ID: founderID}, want: true},
+		{name: "other role ID", tier: Tier{RoleIDs: ids{founderID}}, role: discord.Role{ID: adminID}},
+		{name: "empty tier", tier: Tier{}, role: discord.Role{Permissions: discord.PermissionsAll}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tier.matches(tt.role); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  common.AccessLevel
+		member discord.Member
+		want   bool
+	}{
+		{name: "owner without roles", level: common.AccessOwner, member: member(ownerID), want: true},
+		{name: "moderator on moderator ticket", level: common.AccessModerator, member: member(3, modID), want: true},
+		{name: "moderator on staff ticket", level: common.AccessStaff, member: member(3, modID)},
+		{name: "admin on staff ticket", level: common.AccessStaff, member: member(3, adminID), want: true},
+		{name: "admin on owner ticket", level: common.AccessOwner, member: member(3, adminID)},
+		{name: "bot role", level: common.AccessModerator, member: member(3, botID)},
+		{name: "everyone role", level: common.AccessModerator, member: member(3, guildID)},
+		{name: "unknown member", level: common.AccessModerator, member: member(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testPolicy.Allows(tt.level, testGuild, tt.member); got != tt.want {
+				t.Errorf("Allows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeadAndEscalate(t *testing.T) {
+	// without moderators, staff are first in line for moderator tickets and escalations go to admins
+	noMods := testPolicy.Override(Policy{Moderator: Tier{RoleIDs: ids{99}}})
+	tests := []struct {
+		name     string
+		policy   Policy
+		level    common.AccessLevel
+		lead     ids
+		escalate ids
+		owner    bool
+	}{
+		{name: "moderator", policy: testPolicy, level: common.AccessModerator, lead: ids{modID}, escalate: ids{helperID}},
+		{name: "staff", policy: testPolicy, level: common.AccessStaff, lead: ids{helperID}, escalate: ids{adminID}},
+		{name: "admin", policy: testPolicy, level: common.AccessAdmin, lead: ids{adminID}, escalate: ids{founderID}},
+		{name: "owner", policy: testPolicy, level: common.AccessOwner, lead: ids{founderID}, owner: true},
+		{
+			name:     "empty tier is skipped",
+			policy:   noMods,
+			level:    common.AccessModerator,
+			lead:     ids{helperID},
+			escalate: ids{adminID},
+		},
+		{name: "default policy", policy: Default, level: common.AccessModerator, lead: ids{modID}, escalate: ids{adminID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Lead(tt.level, testGuild).RoleIDs(); !slices.Equal(got, tt.lead) {
+				t.Errorf("lead = %v, want %v", got, tt.lead)
+			}
+			a := tt.policy.Escalate(tt.level, testGuild)
+			if got := a.RoleIDs(); !slices.Equal(got, tt.escalate) {
+				t.Errorf("escalate = %v, want %v", got, tt.escalate)
+			}
+			if got := slices.Contains(a.UserIDs, ownerID); got != tt.owner {
+				t.Errorf("escalated to owner = %v, want %v", got, tt.owner)
+			}
+		})
+	}
+}
+
+func TestOverride(t *testing.T) {
+	helpers := Tier{RoleNames: []string{"Helper"}}
+	tests := []struct {
+		name     string
+		override Policy
+		want     Policy
+	}{
+		{name: "empty override keeps the policy", want: Default},
+		{
+			name:     "defined tiers replace",
+			override: Policy{Staff: helpers, Admin: Tier{RoleIDs: ids{adminID}}},
+			want:     Policy{Moderator: Default.Moderator, Staff: helpers, Admin: Tier{RoleIDs: ids{adminID}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Default.Override(tt.override)
+			for _, level := range common.AccessLevels {
+				if !equalTiers(got.Tier(level), tt.want.Tier(level)) {
+					t.Errorf("%s tier = %+v, want %+v", level, got.Tier(level), tt.want.Tier(level))
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{name: "default", policy: Default},
+		{name: "known permissions", policy: Policy{Staff: Tier{Permissions: []string{"kick_members", "ban_members"}}}},
+		{
+			name:    "unknown permission",
+			policy:  Policy{Staff: Tier{Permissions: []string{"kick_members", "fly"}}},
+			wantErr: true,
+		},
+		{name: "unknown owner permission", policy: Policy{Owner: Tier{Permissions: []string{"owner"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func member(userID snowflake.ID, roleIDs ...snowflake.ID) discord.Member {
+	return discord.Member{User: discord.User{ID: userID}, RoleIDs: roleIDs}
+}
+
+func equalTiers(a, b Tier) bool {
+	return slices.Equal(a.Permissions, b.Permissions) && slices.Equal(a.RoleNames, b.RoleNames) &&
+		slices.Equal(a.RoleIDs, b.RoleIDs)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+var (
+	ErrPriorityInvalid   = errors.New("priority is not valid")
+	ErrPriorityUnchanged = errors.New("ticket already has the priority")
+)
+
+// SetTicketPriority changes the priority of an open ticket, refreshes the ticket message and thread name, and
+// announces the change in the ticket thread. Raising the priority pings those the new priority calls for.
+func SetTicketPriority(
+	ctx context.Context, b *Bot, t *store.Ticket, actorID snowflake.ID, priority common.Priority,
+) error {
+	if !t.IsOpen() {
+		return ErrTicketNotOpen
+	}
+	if !priority.Valid() {
+		return ErrPriorityInvalid
+	}
+	if t.Priority == priority {
+		return ErrPriorityUnchanged
+	}
+	previous := t.Priority
+	if err := b.Store.SetTicketPriority(ctx, t.ID, priority); err != nil {
+		return errors.WithMessage(err, "failed to set ticket priority")
+	}
+	t.Priority = priority
+	RecordTicketEvent(ctx, b, store.TicketEvent{
+		TicketID: t.ID,
+		Kind:     store.TicketEventPrioritised,
+		ActorID:  actorID,
+		Detail:   "previously " + string(previous),
+	})
+	if err := UpdateTicketMessage(b, t); err != nil {
+		slog.Warn("Failed to update ticket message", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+	}
+	renameTicketThread(b, t)
+	announcement := fmt.Sprintf("<@%s> set the priority of ticket #%d to %s.", actorID, t.Number, priority)
+	// only raising the priority calls for attention; the actor is not notified of their own mention
+	var mentions *discord.AllowedMentions
+	if priority.Rank() > previous.Rank() {
+		roleIDs, userIDs, err := GetTicketMentions(b, t)
+		if err != nil {
+			return err
+		}
+		if len(roleIDs) > 0 || len(userIDs) > 0 {
+			announcement += "\n-#"
+		}
+		for _, id := range roleIDs {
+			announcement += fmt.Sprintf(" <@&%s>", id)
+		}
+		for _, id := range userIDs {
+			announcement += fmt.Sprintf(" <@%s>", id)
+		}
+	} else {
+		mentions = &discord.AllowedMentions{}
+	}
+	if _, err := b.Client.Rest().CreateMessage(
+		t.ThreadID,
+		discord.NewMessageCreateBuilder().
+			SetContent(announcement).
+			SetAllowedMentions(mentions).
+			Build(),
+	); err != nil {
+		return errors.WithMessage(err, "failed to announce ticket priority")
+	}
+	slog.Info(
+		"Ticket priority set",
+		slog.Int64("ticket_id", t.ID),
+		slog.Int64("number", t.Number),
+		slog.String("priority", string(priority)),
+		slog.String("previous_priority", string(previous)),
+		slog.Any("actor_id", actorID),
+	)
+	return nil
+}
+
+// renameTicketThread renames the ticket thread after its priority, keeping the assignee's name in it if the guild
+// renames threads on claim. Renaming is cosmetic, so failures are logged rather than returned.
+func renameTicketThread(b *Bot, t *store.Ticket) {
+	var assigneeName string
+	if t.AssigneeID != 0 && b.Cfg.Tickets.ShouldRenameOnClaim(t.GuildID) {
+		if member, ok := b.Client.Caches().Member(t.GuildID, t.AssigneeID); ok {
+			assigneeName = member.EffectiveName()
+		} else if member, err := b.Client.Rest().GetMember(t.GuildID, t.AssigneeID); err == nil {
+			assigneeName = member.EffectiveName()
+		} else {
+			slog.Warn("Failed to get ticket assignee", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+		}
+	}
+	name := TicketThreadName(b, t, assigneeName)
+	if _, err := b.Client.Rest().UpdateChannel(t.ThreadID, discord.GuildThreadUpdate{Name: &name}); err != nil {
+		slog.Warn("Failed to rename ticket thread", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+	}
+}
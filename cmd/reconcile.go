@@ -0,0 +1,467 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	// bulkDeleteMaxAge is how old a message may be for Discord to delete it in bulk.
+	bulkDeleteMaxAge = 14 * 24 * time.Hour
+	// bulkDeleteMaxMessages is the most messages Discord deletes in bulk at once, and bulkDeleteMinMessages the
+	// fewest.
+	bulkDeleteMaxMessages = 100
+	bulkDeleteMinMessages = 2
+	// messagesPageSize is the most messages Discord returns at once.
+	messagesPageSize = 100
+	// maxPanelSearchPages bounds how far back the support channel's history is searched for the bot's panels
+	maxPanelSearchPages = 10
+	// helpMessageMarker is the footer of the help message, which earlier versions posted without the panel's
+	// components
+	helpMessageMarker = "-# TicketsPlease Version:"
+)
+
+// SupportChannelPlan is the difference between the guild's support channel as it is and as the guild's settings,
+// access policy and categories say it should be. Only the parts which differ are changed when it is applied.
+type SupportChannelPlan struct {
+	GuildID snowflake.ID
+	// ChannelID is the support channel, or 0 if it has to be created
+	ChannelID snowflake.ID
+	// Channel is the support channel as it is, or nil if it has to be created
+	Channel discord.GuildMessageChannel
+
+	Name         string
+	Topic        string
+	Overwrites   []discord.PermissionOverwrite
+	NameChanged  bool
+	TopicChanged bool
+	TypeChanged  bool
+	// AddedOverwrites and ChangedOverwrites hold the desired overwrites; RemovedOverwrites the current ones, limited
+	// to those the bot set itself
+	AddedOverwrites   []discord.PermissionOverwrite
+	ChangedOverwrites []discord.PermissionOverwrite
+	RemovedOverwrites []discord.PermissionOverwrite
+
+	PanelContent    string
+	PanelComponents []discord.ContainerComponent
+	// PanelID is the posted panel, or 0 if it has to be posted
+	PanelID   snowflake.ID
+	EditPanel bool
+	PinPanel  bool
+	// ObsoleteMessages are earlier copies of the panel or help message and the bot's notices of pinning them;
+	// nobody else's messages are ever touched, nor are any other messages of the bot's, nor ticket threads
+	ObsoleteMessages []discord.Message
+
+	// roles of the guild, to name the roles of permission overwrites in reports
+	roles []discord.Role
+	// rememberedChannelID is the support channel stored in the guild's settings
+	rememberedChannelID snowflake.ID
+}
+
+// channelChanged reports whether the channel itself has to be created or updated.
+func (p *SupportChannelPlan) channelChanged() bool {
+	return p.ChannelID == 0 || p.NameChanged || p.TopicChanged || p.TypeChanged || p.overwritesChanged()
+}
+
+func (p *SupportChannelPlan) overwritesChanged() bool {
+	return len(p.AddedOverwrites)+len(p.ChangedOverwrites)+len(p.RemovedOverwrites) > 0
+}
+
+// Empty reports whether the support channel already is as it should be.
+func (p *SupportChannelPlan) Empty() bool {
+	return !p.channelChanged() && p.PanelID != 0 && !p.EditPanel && !p.PinPanel && len(p.ObsoleteMessages) == 0
+}
+
+// ReconcileSupportChannel brings the guild's support channel and its ticket panel in line with the guild's
+// settings, and remembers the channel in them. channelID is the support channel if already known, for instance as
+// found before the settings were reset, or 0 to look it up (or create it) afresh.
+func ReconcileSupportChannel(b *Bot, guildID snowflake.ID, channelID snowflake.ID) error {
+	p, err := PlanSupportChannel(b, guildID, channelID)
+	if err != nil {
+		return err
+	}
+	if p.Empty() {
+		slog.Info("Support channel is already configured", slog.Any("guild_id", guildID))
+	} else if err = p.Apply(b); err != nil {
+		return err
+	}
+	if p.ChannelID == p.rememberedChannelID {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = b.Store.SetSupportChannel(ctx, guildID, p.ChannelID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PlanSupportChannel works out what has to change for the guild's support channel to be as it should be,
+// without changing anything.
+func PlanSupportChannel(b *Bot, guildID snowflake.ID, channelID snowflake.ID) (*SupportChannelPlan, error) {
+	g, err := GetPolicyGuild(b, guildID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get guild roles")
+	}
+	content, components, err := SupportPanel(b, guildID)
+	if err != nil {
+		return nil, err
+	}
+	settings := GuildSettings(b, guildID)
+	name, topic := SupportChannelSettings(settings)
+	p := &SupportChannelPlan{
+		GuildID:             guildID,
+		ChannelID:           channelID,
+		Name:                name,
+		Topic:               topic,
+		Overwrites:          getSupportChannelOverrides(b, g),
+		roles:               g.Roles,
+		rememberedChannelID: settings.SupportChannelID,
+		PanelContent:        content,
+		PanelComponents:     components,
+	}
+	if p.ChannelID == 0 {
+		if p.ChannelID, err = GetSupportChannel(b, &guildID); err != nil {
+			return nil, errors.WithMessage(err, "failed to get support channel")
+		}
+	}
+	if p.ChannelID == 0 && p.rememberedChannelID == 0 {
+		if p.ChannelID, err = findLegacySupportChannel(b, guildID, name); err != nil {
+			return nil, errors.WithMessage(err, "failed to find support channel")
+		}
+	}
+	if p.ChannelID == 0 {
+		return p, nil
+	}
+	channel, err := getGuildChannel(b, guildID, p.ChannelID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get support channel")
+	}
+	if channel == nil {
+		return nil, errors.Errorf("support channel %s does not exist", p.ChannelID)
+	}
+	var ok bool
+	if p.Channel, ok = channel.(discord.GuildMessageChannel); !ok {
+		return nil, errors.Errorf("support channel %s is not a guild message channel", p.ChannelID)
+	}
+	p.planChannel()
+	if err = p.planMessages(b); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// supportChannelDrifted reports whether the support channel's name, topic, type or permission overwrites differ from
+// how they should be, without looking at its messages. Should the desired state be unknown, the channel is reported
+// as drifted.
+func supportChannelDrifted(b *Bot, guildID snowflake.ID, channel discord.GuildChannel) bool {
+	c, ok := channel.(discord.GuildMessageChannel)
+	if !ok {
+		return true
+	}
+	g, err := GetPolicyGuild(b, guildID)
+	if err != nil {
+		slog.Warn("Failed to get guild roles", slog.Any("guild_id", guildID), slog.Any("err", err))
+		return true
+	}
+	name, topic := SupportChannelSettings(GuildSettings(b, guildID))
+	p := &SupportChannelPlan{
+		GuildID:    guildID,
+		ChannelID:  c.ID(),
+		Channel:    c,
+		Name:       name,
+		Topic:      topic,
+		Overwrites: getSupportChannelOverrides(b, g),
+	}
+	p.planChannel()
+	return p.channelChanged()
+}
+
+// planChannel compares the channel's name, topic, type and permission overwrites with the desired ones.
+func (p *SupportChannelPlan) planChannel() {
+	p.NameChanged = p.Channel.Name() != p.Name
+	var topic string
+	if t := p.Channel.Topic(); t != nil {
+		topic = *t
+	}
+	p.TopicChanged = topic != p.Topic
+	p.TypeChanged = p.Channel.Type() != discord.ChannelTypeGuildText
+	current := p.Channel.PermissionOverwrites()
+	for _, o := range p.Overwrites {
+		i := slices.IndexFunc(current, func(c discord.PermissionOverwrite) bool { return sameOverwriteTarget(c, o) })
+		if i < 0 {
+			p.AddedOverwrites = append(p.AddedOverwrites, o)
+		} else if !sameOverwritePermissions(current[i], o) {
+			p.ChangedOverwrites = append(p.ChangedOverwrites, o)
+		}
+	}
+	for _, c := range current {
+		if !isManagedOverwrite(c) {
+			continue
+		}
+		if !slices.ContainsFunc(p.Overwrites, func(o discord.PermissionOverwrite) bool { return sameOverwriteTarget(c, o) }) {
+			p.RemovedOverwrites = append(p.RemovedOverwrites, c)
+		}
+	}
+}
+
+// planMessages finds the panel among the bot's messages in the channel and decides whether it needs editing,
+// pinning or posting; every other copy of it is obsolete, as are the bot's notices of pinning them. Only messages
+// recognisable as the panel or the help message count as copies.
+func (p *SupportChannelPlan) planMessages(b *Bot) error {
+	botID, err := botUserID(b)
+	if err != nil {
+		return err
+	}
+	var (
+		panels, notices []discord.Message
+		before          snowflake.ID
+	)
+	for range maxPanelSearchPages {
+		page, err := b.Client.Rest().GetMessages(p.ChannelID, 0, before, 0, messagesPageSize)
+		if err != nil {
+			return errors.WithMessage(err, "failed to get existing support channel messages")
+		}
+		for _, m := range page {
+			if m.Author.ID != botID {
+				continue
+			}
+			switch {
+			case m.Type == discord.MessageTypeDefault && (isPanelMessage(m) || isHelpMessage(m)):
+				panels = append(panels, m)
+			case m.Type == discord.MessageTypeChannelPinnedMessage && m.MessageReference != nil:
+				notices = append(notices, m)
+			}
+		}
+		if len(page) < messagesPageSize {
+			break
+		}
+		before = page[len(page)-1].ID
+	}
+	for _, n := range notices {
+		if id := n.MessageReference.MessageID; id != nil && slices.ContainsFunc(panels, func(m discord.Message) bool {
+			return m.ID == *id
+		}) {
+			p.ObsoleteMessages = append(p.ObsoleteMessages, n)
+		}
+	}
+	if len(panels) == 0 {
+		return nil
+	}
+	// prefer the pinned panel, then one showing the panel's components, then the oldest message, which is what
+	// earlier versions posted
+	keep := slices.IndexFunc(panels, func(m discord.Message) bool { return m.Pinned })
+	if keep < 0 {
+		keep = slices.IndexFunc(panels, isPanelMessage)
+	}
+	if keep < 0 {
+		keep = len(panels) - 1
+	}
+	panel := panels[keep]
+	p.PanelID = panel.ID
+	p.EditPanel = !panelMatches(panel, p.PanelContent, p.PanelComponents)
+	p.PinPanel = !panel.Pinned
+	p.ObsoleteMessages = append(p.ObsoleteMessages, slices.Delete(panels, keep, keep+1)...)
+	return nil
+}
+
+// Apply makes the planned changes.
+func (p *SupportChannelPlan) Apply(b *Bot) error {
+	if err := p.applyChannel(b); err != nil {
+		return err
+	}
+	if err := p.applyPanel(b); err != nil {
+		return err
+	}
+	if err := deleteMessages(b, p.ChannelID, p.ObsoleteMessages); err != nil {
+		return err
+	}
+	slog.Info("Support channel reconciled", slog.Any("guild_id", p.GuildID), slog.Any("channel_id", p.ChannelID))
+	return nil
+}
+
+func (p *SupportChannelPlan) applyChannel(b *Bot) error {
+	if p.ChannelID == 0 {
+		c, err := b.Client.Rest().CreateGuildChannel(
+			p.GuildID,
+			discord.GuildTextChannelCreate{
+				Name:                 p.Name,
+				Topic:                p.Topic,
+				PermissionOverwrites: p.Overwrites,
+			},
+		)
+		if err != nil {
+			return errors.WithMessage(err, "failed to create support channel")
+		}
+		p.ChannelID = c.ID()
+		return nil
+	}
+	if !p.channelChanged() {
+		return nil
+	}
+	var update discord.GuildTextChannelUpdate
+	if p.NameChanged {
+		update.Name = &p.Name
+	}
+	if p.TopicChanged {
+		update.Topic = &p.Topic
+	}
+	if p.TypeChanged {
+		channelType := discord.ChannelTypeGuildText
+		update.Type = &channelType
+	}
+	if p.overwritesChanged() {
+		overwrites := p.appliedOverwrites()
+		update.PermissionOverwrites = &overwrites
+	}
+	if _, err := b.Client.Rest().UpdateChannel(p.ChannelID, update); err != nil {
+		return errors.WithMessage(err, "failed to update support channel")
+	}
+	return nil
+}
+
+// appliedOverwrites returns the desired permission overwrites along with those of the channel's current ones which
+// are neither replaced nor removed, since Discord replaces a channel's overwrites as a whole.
+func (p *SupportChannelPlan) appliedOverwrites() []discord.PermissionOverwrite {
+	overwrites := slices.Clone(p.Overwrites)
+	for _, c := range p.Channel.PermissionOverwrites() {
+		sameTarget := func(o discord.PermissionOverwrite) bool { return sameOverwriteTarget(c, o) }
+		if !slices.ContainsFunc(p.Overwrites, sameTarget) && !slices.ContainsFunc(p.RemovedOverwrites, sameTarget) {
+			overwrites = append(overwrites, c)
+		}
+	}
+	return overwrites
+}
+
+func (p *SupportChannelPlan) applyPanel(b *Bot) error {
+	if p.PanelID == 0 {
+		m, err := b.Client.Rest().CreateMessage(
+			p.ChannelID,
+			discord.NewMessageCreateBuilder().
+				SetContent(p.PanelContent).
+				AddContainerComponents(p.PanelComponents...).
+				Build(),
+		)
+		if err != nil {
+			return errors.WithMessage(err, "failed to send support panel")
+		}
+		p.PanelID = m.ID
+		p.PinPanel = true
+	} else if p.EditPanel {
+		if _, err := b.Client.Rest().UpdateMessage(
+			p.ChannelID,
+			p.PanelID,
+			discord.NewMessageUpdateBuilder().
+				SetContent(p.PanelContent).
+				SetContainerComponents(p.PanelComponents...).
+				Build(),
+		); err != nil {
+			return errors.WithMessage(err, "failed to update support panel")
+		}
+	}
+	if p.PinPanel {
+		// pinning is a nicety which keeps the panel easy to find; the panel works without it
+		if err := b.Client.Rest().PinMessage(p.ChannelID, p.PanelID); err != nil {
+			slog.Warn("Failed to pin support panel", slog.Any("guild_id", p.GuildID), slog.Any("err", err))
+		}
+	}
+	return nil
+}
+
+// deleteMessages deletes the messages from the channel, in bulk where Discord allows it: a hundred recent messages at
+// a time.
+func deleteMessages(b *Bot, channelID snowflake.ID, messages []discord.Message) error {
+	var recent, old []snowflake.ID
+	for _, m := range messages {
+		if time.Since(m.ID.Time()) < bulkDeleteMaxAge-time.Hour {
+			recent = append(recent, m.ID)
+		} else {
+			old = append(old, m.ID)
+		}
+	}
+	for chunk := range slices.Chunk(recent, bulkDeleteMaxMessages) {
+		if len(chunk) < bulkDeleteMinMessages {
+			old = append(old, chunk...)
+			continue
+		}
+		if err := b.Client.Rest().BulkDeleteMessages(channelID, chunk); err != nil {
+			return errors.WithMessage(err, "failed to delete obsolete messages")
+		}
+	}
+	for _, id := range old {
+		if err := b.Client.Rest().DeleteMessage(channelID, id); err != nil {
+			return errors.WithMessage(err, "failed to delete obsolete message")
+		}
+	}
+	return nil
+}
+
+// isPanelMessage reports whether the message carries the ticket panel's components.
+func isPanelMessage(m discord.Message) bool {
+	for _, row := range m.Components {
+		r, ok := row.(discord.ActionRowComponent)
+		if !ok {
+			continue
+		}
+		for _, c := range r.Components() {
+			if id := c.ID(); id == PanelCategoryID || id == PanelHelpID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isHelpMessage reports whether the message is the help message, as earlier versions posted it in place of the panel.
+func isHelpMessage(m discord.Message) bool {
+	return strings.Contains(m.Content, helpMessageMarker)
+}
+
+// isManagedOverwrite reports whether the permission overwrite is one the bot sets on the support channel, so that
+// overwrites others have set are left alone. The @everyone overwrite is always wanted, so it is never removed.
+func isManagedOverwrite(o discord.PermissionOverwrite) bool {
+	if o.Type() != discord.PermissionOverwriteTypeRole {
+		return false
+	}
+	allow, deny := overwritePermissions(o)
+	return deny == discord.PermissionsNone && (allow == staffOverwritePermissions || allow == botOverwritePermissions)
+}
+
+func sameOverwriteTarget(a discord.PermissionOverwrite, b discord.PermissionOverwrite) bool {
+	return a.Type() == b.Type() && a.ID() == b.ID()
+}
+
+func sameOverwritePermissions(a discord.PermissionOverwrite, b discord.PermissionOverwrite) bool {
+	aAllow, aDeny := overwritePermissions(a)
+	bAllow, bDeny := overwritePermissions(b)
+	return aAllow == bAllow && aDeny == bDeny
+}
+
+func overwritePermissions(o discord.PermissionOverwrite) (allow discord.Permissions, deny discord.Permissions) {
+	switch o := o.(type) {
+	case discord.RolePermissionOverwrite:
+		return o.Allow, o.Deny
+	case discord.MemberPermissionOverwrite:
+		return o.Allow, o.Deny
+	}
+	return 0, 0
+}
+
+// botUserID returns the bot's user ID, which is only cached once the gateway is open.
+func botUserID(b *Bot) (snowflake.ID, error) {
+	if id := b.Client.ID(); id != 0 {
+		return id, nil
+	}
+	u, err := b.Client.Rest().GetCurrentUser("")
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to get current user")
+	}
+	return u.ID, nil
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"context"
+	"time"
+)
+
+// SchedulerInterval is how often the scheduler checks open tickets.
+const SchedulerInterval = 5 * time.Minute
+
+// RunScheduler periodically runs the checks which act on tickets without anyone interacting with them, until ctx
+// is done.
+func RunScheduler(ctx context.Context, b *Bot) {
+	ticker := time.NewTicker(SchedulerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			CheckInactivity(ctx, b, now.UTC())
+			CheckSLAs(ctx, b, now.UTC())
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/store"
+	"github.com/kapparina/ticketsplease/cmd/templates"
+)
+
+// GuildSettings returns the settings the guild's admins have stored, which the store keeps cached. Failures are
+// logged and the defaults returned instead, so that a storage hiccup degrades to the configured behaviour rather
+// than failing outright.
+func GuildSettings(b *Bot, guildID snowflake.ID) store.GuildSettings {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	settings, err := b.Store.GetGuildSettings(ctx, guildID)
+	if err != nil {
+		slog.Error("Failed to get guild settings", slog.Any("guild_id", guildID), slog.Any("err", err))
+		return store.GuildSettings{GuildID: guildID}
+	}
+	return *settings
+}
+
+// TicketLimitError is returned when a member already has as many open tickets as their guild allows.
+type TicketLimitError struct {
+	Limit int
+}
+
+func (e TicketLimitError) Error() string {
+	return fmt.Sprintf("member already has %d open tickets", e.Limit)
+}
+
+// CheckTicketLimit returns a TicketLimitError if the member may not open another ticket in the guild. It spares the
+// member filling in the ticket form in vain; the limit is enforced when the ticket is stored.
+func CheckTicketLimit(ctx context.Context, b *Bot, guildID snowflake.ID, memberID snowflake.ID) error {
+	limit := GuildSettings(b, guildID).MaxOpenTickets
+	if limit <= 0 {
+		return nil
+	}
+	open, err := b.Store.ListTickets(ctx, store.TicketFilter{
+		GuildID:  guildID,
+		OpenerID: memberID,
+		Status:   store.TicketStatusOpen,
+		Limit:    limit,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed to count open tickets")
+	}
+	if len(open) >= limit {
+		return TicketLimitError{Limit: limit}
+	}
+	return nil
+}
+
+// SupportChannelSettings returns the name and topic of the guild's support channel.
+func SupportChannelSettings(settings store.GuildSettings) (name string, topic string) {
+	name, topic = SupportChannelName, SupportChannelTopic
+	if settings.SupportChannelName != "" {
+		name = settings.SupportChannelName
+	}
+	if settings.SupportChannelTopic != "" {
+		topic = settings.SupportChannelTopic
+	}
+	return name, topic
+}
+
+// LogChannel returns the channel ticket records such as transcripts are posted to in the guild, or 0 if none.
+func LogChannel(b *Bot, guildID snowflake.ID) snowflake.ID {
+	if id := GuildSettings(b, guildID).LogChannelID; id != 0 {
+		return id
+	}
+	return b.Cfg.Tickets.LogChannel(guildID)
+}
+
+// EnabledCategories returns the categories members of the guild may open new tickets in.
+func EnabledCategories(b *Bot, guildID snowflake.ID) common.Categories {
+	settings := GuildSettings(b, guildID)
+	var enabled common.Categories
+	for _, c := range b.Cfg.GuildCategories(guildID).Enabled() {
+		if settings.CategoryEnabled(c.Title) {
+			enabled = append(enabled, c)
+		}
+	}
+	return enabled
+}
+
+// PopulateSettingsContent renders the guild's settings, with defaults filled in, for display to its admins.
+func PopulateSettingsContent(b *Bot, settings store.GuildSettings) (string, error) {
+	name, topic := SupportChannelSettings(settings)
+	logChannelID := settings.LogChannelID
+	if logChannelID == 0 {
+		logChannelID = b.Cfg.Tickets.LogChannel(settings.GuildID)
+	}
+	data := templates.TicketSettingsData{
+		SupportChannelName:  name,
+		SupportChannelTopic: topic,
+		LogChannelDefault:   settings.LogChannelID == 0 && logChannelID != 0,
+		DisabledCategories:  settings.DisabledCategories,
+		Pings:               settings.Pings(),
+		MaxOpenTickets:      settings.MaxOpenTickets,
+	}
+	if logChannelID != 0 {
+		data.LogChannelID = logChannelID.String()
+	}
+	for _, id := range settings.StaffRoleIDs {
+		data.StaffRoles = append(data.StaffRoles, id.String())
+	}
+	return templates.PopulateTicketSettingsData(data)
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/store"
+	"github.com/kapparina/ticketsplease/cmd/templates"
+)
+
+// SLAState is how a ticket stands against one of its SLA deadlines.
+type SLAState struct {
+	Target   common.SLATarget
+	Deadline time.Time
+	// MetAt is when the target was met, or nil if it has not been yet
+	MetAt *time.Time
+	// Level is empty while the ticket is on track
+	Level store.SLALevel
+}
+
+// TicketSLA returns how the ticket stands against each deadline its SLA policy sets at the given time. The
+// deadlines are counted from the ticket's creation, also for tickets which were reopened, and only within the
+// guild's business hours.
+func TicketSLA(b *Bot, t *store.Ticket, now time.Time) []SLAState {
+	p := b.Cfg.SLAPolicy(t.GuildID, t.Category)
+	schedule := b.Cfg.BusinessHours(t.GuildID)
+	var states []SLAState
+	for _, target := range common.SLATargets {
+		budget := p.Budget(target, t.Priority)
+		if budget == 0 {
+			continue
+		}
+		s := SLAState{Target: target, Deadline: schedule.Add(t.CreatedAt, budget), MetAt: slaMetAt(t, target)}
+		warn := p.WarnBudget(target, t.Priority)
+		switch {
+		case s.MetAt != nil:
+			if s.MetAt.After(s.Deadline) {
+				s.Level = store.SLABreached
+			}
+		case now.After(s.Deadline):
+			s.Level = store.SLABreached
+		case warn > 0 && !now.Before(schedule.Add(t.CreatedAt, warn)):
+			s.Level = store.SLANearing
+		}
+		states = append(states, s)
+	}
+	return states
+}
+
+// slaMetAt returns when the ticket met the target, or nil if it has not yet.
+func slaMetAt(t *store.Ticket, target common.SLATarget) *time.Time {
+	switch target {
+	case common.SLAFirstResponse:
+		return t.FirstStaffResponseAt
+	case common.SLAResolution:
+		if !t.IsOpen() {
+			return t.ClosedAt
+		}
+	}
+	return nil
+}
+
+// SLAFlag returns the marker shown next to a ticket in ticket lists: one for a breached deadline, another for one
+// which is near, and nothing for a ticket on track.
+func SLAFlag(states []SLAState) string {
+	flag := ""
+	for _, s := range states {
+		switch s.Level {
+		case store.SLABreached:
+			return "🚨"
+		case store.SLANearing:
+			flag = "⚠️"
+		}
+	}
+	return flag
+}
+
+// CheckSLAs escalates the open tickets nearing or breaching one of their SLA deadlines, most urgent first. Failures
+// are logged per ticket so that one broken ticket does not hold up the others.
+func CheckSLAs(ctx context.Context, b *Bot, now time.Time) {
+	tickets, err := b.Store.ListTickets(ctx, store.TicketFilter{Status: store.TicketStatusOpen, ByPriority: true})
+	if err != nil {
+		slog.Error("Failed to list open tickets", slog.Any("err", err))
+		return
+	}
+	for i := range tickets {
+		t := &tickets[i]
+		if b.Client.Caches().IsGuildUnavailable(t.GuildID) {
+			continue
+		}
+		if err = RecordSLA(ctx, b, t, now, true); err != nil {
+			slog.Error("Failed to check ticket SLA", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+		}
+	}
+}
+
+// RecordSLA stores the ticket's breaches, and escalations of deadlines it is nearing, which have not been stored
+// yet. If escalate is set, each new escalation of a deadline the ticket has yet to meet is also posted to the ticket's
+// thread, pinging the tier above the one responsible for the ticket.
+func RecordSLA(ctx context.Context, b *Bot, t *store.Ticket, now time.Time, escalate bool) error {
+	for _, s := range TicketSLA(b, t, now) {
+		if s.Level == "" {
+			continue
+		}
+		recorded, err := b.Store.AddSLAEscalation(ctx, &store.SLAEscalation{
+			TicketID:  t.ID,
+			GuildID:   t.GuildID,
+			Category:  t.Category,
+			Target:    string(s.Target),
+			Level:     s.Level,
+			Deadline:  s.Deadline,
+			CreatedAt: now,
+		})
+		if err != nil {
+			return err
+		}
+		if !recorded || !escalate || s.MetAt != nil {
+			continue
+		}
+		if err = escalateTicket(ctx, b, t, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escalateTicket posts the ticket's SLA state to its thread, pinging the tier above the one responsible for the
+// ticket unless the guild has turned pings off.
+func escalateTicket(ctx context.Context, b *Bot, t *store.Ticket, s SLAState) error {
+	data := templates.TicketEscalatedData{
+		Number:   t.Number,
+		Target:   s.Target.Label(),
+		Deadline: s.Deadline.Unix(),
+		Breached: s.Level == store.SLABreached,
+	}
+	mentions := &discord.AllowedMentions{}
+	if GuildSettings(b, t.GuildID).Pings() {
+		g, err := GetPolicyGuild(b, t.GuildID)
+		if err != nil {
+			return err
+		}
+		audience := b.Cfg.GuildPolicy(t.GuildID).Escalate(GetTicketCategory(b, t).Access, g)
+		mentions.Roles = audience.RoleIDs()
+		mentions.Users = audience.UserIDs
+		for _, id := range mentions.Roles {
+			data.Moderators = append(data.Moderators, id.String())
+		}
+		for _, id := range mentions.Users {
+			data.Members = append(data.Members, id.String())
+		}
+	}
+	content, err := templates.PopulateTicketEscalatedData(data)
+	if err != nil {
+		return errors.WithMessage(err, "failed to populate escalation")
+	}
+	if _, err = b.Client.Rest().CreateMessage(
+		t.ThreadID,
+		discord.NewMessageCreateBuilder().
+			SetContent(content).
+			SetAllowedMentions(mentions).
+			Build(),
+	); err != nil {
+		return errors.WithMessage(err, "failed to send escalation")
+	}
+	RecordTicketEvent(ctx, b, store.TicketEvent{
+		TicketID: t.ID,
+		Kind:     store.TicketEventEscalated,
+		Detail:   s.Target.Label() + " " + string(s.Level),
+	})
+	slog.Info(
+		"Ticket escalated",
+		slog.Int64("ticket_id", t.ID),
+		slog.String("target", string(s.Target)),
+		slog.String("level", string(s.Level)),
+	)
+	return nil
+}
+
+// PopulateSLAContent describes how the ticket stands against its SLA deadlines, for its support team.
+func PopulateSLAContent(b *Bot, t *store.Ticket, now time.Time) (string, error) {
+	data := templates.TicketSLAData{Number: t.Number}
+	for _, s := range TicketSLA(b, t, now) {
+		target := templates.SLATarget{
+			Target:   s.Target.Label(),
+			Deadline: s.Deadline.Unix(),
+			Flag:     SLAFlag([]SLAState{s}),
+		}
+		if s.MetAt != nil {
+			target.MetAt = s.MetAt.Unix()
+		}
+		switch {
+		case s.Level == store.SLABreached:
+			target.Status = "breached"
+		case s.Level == store.SLANearing:
+			target.Status = "nearing its deadline"
+		case s.MetAt != nil:
+			target.Status = "met"
+		default:
+			target.Status = "on track"
+		}
+		data.Targets = append(data.Targets, target)
+	}
+	return templates.PopulateTicketSLAData(data)
+}
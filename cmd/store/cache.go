@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// settingsCache is a Store which keeps each guild's settings in memory once read, as they are looked up for almost
+// every interaction but rarely change. Every write of a guild's settings through the store, including deleting the
+// guild, forgets the guild's cached settings.
+type settingsCache struct {
+	Store
+	mu       sync.Mutex
+	settings map[snowflake.ID]GuildSettings
+	// generation counts the forgotten settings, so that settings read before a write are not cached after it
+	generation uint64
+}
+
+// WithSettingsCache wraps the store to cache guild settings in memory.
+func WithSettingsCache(s Store) Store {
+	return &settingsCache{Store: s, settings: make(map[snowflake.ID]GuildSettings)}
+}
+
+func (c *settingsCache) GetGuildSettings(ctx context.Context, guildID snowflake.ID) (*GuildSettings, error) {
+	c.mu.Lock()
+	if s, ok := c.settings[guildID]; ok {
+		c.mu.Unlock()
+		return cloneSettings(s), nil
+	}
+	generation := c.generation
+	c.mu.Unlock()
+	s, err := c.Store.GetGuildSettings(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if c.generation == generation {
+		c.settings[guildID] = *cloneSettings(*s)
+	}
+	c.mu.Unlock()
+	return s, nil
+}
+
+func (c *settingsCache) SaveGuildSettings(ctx context.Context, s *GuildSettings) error {
+	defer c.forget(s.GuildID)
+	return c.Store.SaveGuildSettings(ctx, s)
+}
+
+func (c *settingsCache) SetSupportChannel(ctx context.Context, guildID snowflake.ID, channelID snowflake.ID) error {
+	defer c.forget(guildID)
+	return c.Store.SetSupportChannel(ctx, guildID, channelID)
+}
+
+func (c *settingsCache) DeleteGuildSettings(ctx context.Context, guildID snowflake.ID) error {
+	defer c.forget(guildID)
+	return c.Store.DeleteGuildSettings(ctx, guildID)
+}
+
+func (c *settingsCache) DeleteGuild(ctx context.Context, guildID snowflake.ID) error {
+	defer c.forget(guildID)
+	return c.Store.DeleteGuild(ctx, guildID)
+}
+
+func (c *settingsCache) forget(guildID snowflake.ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.settings, guildID)
+	c.generation++
+}
+
+// cloneSettings copies the settings, so that callers changing them leave the cached copy as it is.
+func cloneSettings(s GuildSettings) *GuildSettings {
+	s.StaffRoleIDs = slices.Clone(s.StaffRoleIDs)
+	s.DisabledCategories = slices.Clone(s.DisabledCategories)
+	return &s
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	version int
+	name    string
+	query   string
+}
+
+// loadMigrations reads the embedded migration files, ordered by their numeric prefix.
+// Files are expected to be named NNNN_description.sql.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read migrations")
+	}
+	migrations := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, errors.Errorf("migration %q is missing a version prefix", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "migration %q has an invalid version prefix", name)
+		}
+		query, err := migrationFiles.ReadFile("migrations/" + name)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read migration %q", name)
+		}
+		migrations = append(migrations, migration{version: version, name: name, query: string(query)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// pendingMigrations counts the embedded migrations newer than the recorded schema version, without applying them.
+func pendingMigrations(ctx context.Context, db *sql.DB) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	var exists bool
+	if err = db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`,
+	).Scan(&exists); err != nil {
+		return 0, errors.WithMessage(err, "failed to read schema version")
+	}
+	if !exists {
+		return len(migrations), nil
+	}
+	var current int
+	if err = db.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`,
+	).Scan(&current); err != nil {
+		return 0, errors.WithMessage(err, "failed to read schema version")
+	}
+	var pending int
+	for _, m := range migrations {
+		if m.version > current {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// migrate applies every embedded migration newer than the recorded schema version.
+// Each migration runs in its own transaction alongside the version bump.
+func migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx,
+		`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL)`,
+	); err != nil {
+		return errors.WithMessage(err, "failed to create schema_migrations table")
+	}
+	var current int
+	if err := db.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`,
+	).Scan(&current); err != nil {
+		return errors.WithMessage(err, "failed to read schema version")
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		slog.Info("Applying migration", slog.Int("version", m.version), slog.String("name", m.name))
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return errors.WithMessage(err, "failed to begin migration")
+		}
+		if _, err = tx.ExecContext(ctx, m.query); err != nil {
+			_ = tx.Rollback()
+			return errors.WithMessagef(err, "failed to apply migration %q", m.name)
+		}
+		if _, err = tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name,
+		); err != nil {
+			_ = tx.Rollback()
+			return errors.WithMessagef(err, "failed to record migration %q", m.name)
+		}
+		if err = tx.Commit(); err != nil {
+			return errors.WithMessagef(err, "failed to commit migration %q", m.name)
+		}
+	}
+	return nil
+}
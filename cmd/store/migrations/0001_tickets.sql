@@ -0,0 +1,19 @@
+CREATE TABLE tickets
+(
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    guild_id       INTEGER NOT NULL,
+    channel_id     INTEGER NOT NULL,
+    thread_id      INTEGER NOT NULL UNIQUE,
+    opener_id      INTEGER NOT NULL,
+    category       TEXT    NOT NULL,
+    subject        TEXT    NOT NULL,
+    content        TEXT    NOT NULL,
+    attachment_url TEXT    NOT NULL DEFAULT '',
+    status         TEXT    NOT NULL DEFAULT 'open',
+    created_at     INTEGER NOT NULL,
+    updated_at     INTEGER NOT NULL,
+    closed_at      INTEGER
+);
+
+CREATE INDEX idx_tickets_guild_status ON tickets (guild_id, status);
+CREATE INDEX idx_tickets_opener ON tickets (guild_id, opener_id);
@@ -0,0 +1,17 @@
+CREATE TABLE guild_ticket_counters
+(
+    guild_id    INTEGER PRIMARY KEY,
+    last_number INTEGER NOT NULL
+);
+
+ALTER TABLE tickets ADD COLUMN number INTEGER NOT NULL DEFAULT 0;
+
+UPDATE tickets
+SET number = (SELECT COUNT(*) FROM tickets t WHERE t.guild_id = tickets.guild_id AND t.id <= tickets.id);
+
+INSERT INTO guild_ticket_counters (guild_id, last_number)
+SELECT guild_id, MAX(number)
+FROM tickets
+GROUP BY guild_id;
+
+CREATE UNIQUE INDEX idx_tickets_guild_number ON tickets (guild_id, number);
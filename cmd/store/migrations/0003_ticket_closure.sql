@@ -0,0 +1,2 @@
+ALTER TABLE tickets ADD COLUMN closed_by INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tickets ADD COLUMN close_reason TEXT NOT NULL DEFAULT '';
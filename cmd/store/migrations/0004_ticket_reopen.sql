@@ -0,0 +1,2 @@
+ALTER TABLE tickets ADD COLUMN reopen_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tickets ADD COLUMN follow_up_of INTEGER NOT NULL DEFAULT 0;
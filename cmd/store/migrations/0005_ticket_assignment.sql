@@ -0,0 +1,16 @@
+ALTER TABLE tickets ADD COLUMN opener_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE tickets ADD COLUMN message_id INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tickets ADD COLUMN assignee_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE ticket_events
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    ticket_id  INTEGER NOT NULL REFERENCES tickets (id) ON DELETE CASCADE,
+    kind       TEXT    NOT NULL,
+    actor_id   INTEGER NOT NULL,
+    target_id  INTEGER NOT NULL DEFAULT 0,
+    detail     TEXT    NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX idx_ticket_events_ticket ON ticket_events (ticket_id, created_at);
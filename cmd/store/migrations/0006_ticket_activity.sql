@@ -0,0 +1,5 @@
+ALTER TABLE tickets ADD COLUMN last_user_activity_at INTEGER;
+ALTER TABLE tickets ADD COLUMN last_staff_activity_at INTEGER;
+ALTER TABLE tickets ADD COLUMN first_staff_response_at INTEGER;
+ALTER TABLE tickets ADD COLUMN user_message_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tickets ADD COLUMN staff_message_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+CREATE TABLE guild_settings
+(
+    guild_id              INTEGER PRIMARY KEY,
+    support_channel_name  TEXT    NOT NULL DEFAULT '',
+    support_channel_topic TEXT    NOT NULL DEFAULT '',
+    log_channel_id        INTEGER NOT NULL DEFAULT 0,
+    staff_role_ids        TEXT    NOT NULL DEFAULT '[]',
+    disabled_categories   TEXT    NOT NULL DEFAULT '[]',
+    ping_mode             TEXT    NOT NULL DEFAULT '',
+    max_open_tickets      INTEGER NOT NULL DEFAULT 0,
+    updated_at            INTEGER NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE tickets ADD COLUMN answers TEXT NOT NULL DEFAULT '[]';
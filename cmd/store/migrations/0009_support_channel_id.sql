@@ -0,0 +1 @@
+ALTER TABLE guild_settings ADD COLUMN support_channel_id INTEGER NOT NULL DEFAULT 0;
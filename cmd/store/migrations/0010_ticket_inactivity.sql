@@ -0,0 +1,3 @@
+ALTER TABLE tickets ADD COLUMN inactivity_stage INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tickets ADD COLUMN inactivity_stage_at INTEGER;
+ALTER TABLE tickets ADD COLUMN inactivity_paused INTEGER NOT NULL DEFAULT 0;
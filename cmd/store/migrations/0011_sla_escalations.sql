@@ -0,0 +1,14 @@
+CREATE TABLE sla_escalations
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    ticket_id  INTEGER NOT NULL REFERENCES tickets (id) ON DELETE CASCADE,
+    guild_id   INTEGER NOT NULL,
+    category   TEXT    NOT NULL,
+    target     TEXT    NOT NULL,
+    level      TEXT    NOT NULL,
+    deadline   INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    UNIQUE (ticket_id, target, level)
+);
+
+CREATE INDEX idx_sla_escalations_guild ON sla_escalations (guild_id, created_at);
@@ -0,0 +1 @@
+ALTER TABLE tickets ADD COLUMN priority TEXT NOT NULL DEFAULT 'normal';
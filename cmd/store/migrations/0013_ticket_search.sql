@@ -0,0 +1,54 @@
+-- One row per ticket, sharing the ticket's id as its rowid. The triggers keep the ticket's own text in step with the
+-- tickets table; messages are filled in by the store as they are posted and once the ticket is closed.
+CREATE VIRTUAL TABLE ticket_search USING fts5
+(
+    subject,
+    content,
+    category,
+    messages,
+    tokenize = 'porter unicode61'
+);
+
+INSERT INTO ticket_search (rowid, subject, content, category, messages)
+SELECT id,
+       subject,
+       content || COALESCE((SELECT char(10) || group_concat(value ->> '$.value', char(10)) FROM json_each(answers)), ''),
+       category,
+       ''
+FROM tickets;
+
+CREATE TRIGGER ticket_search_insert
+    AFTER INSERT
+    ON tickets
+BEGIN
+    INSERT INTO ticket_search (rowid, subject, content, category, messages)
+    VALUES (NEW.id,
+            NEW.subject,
+            NEW.content ||
+            COALESCE((SELECT char(10) || group_concat(value ->> '$.value', char(10)) FROM json_each(NEW.answers)), ''),
+            NEW.category,
+            '');
+END;
+
+CREATE TRIGGER ticket_search_update
+    AFTER UPDATE OF subject, content, answers, category
+    ON tickets
+    WHEN OLD.subject IS NOT NEW.subject
+        OR OLD.content IS NOT NEW.content
+        OR OLD.answers IS NOT NEW.answers
+        OR OLD.category IS NOT NEW.category
+BEGIN
+    UPDATE ticket_search
+    SET subject  = NEW.subject,
+        content  = NEW.content ||
+                   COALESCE((SELECT char(10) || group_concat(value ->> '$.value', char(10)) FROM json_each(NEW.answers)), ''),
+        category = NEW.category
+    WHERE rowid = NEW.id;
+END;
+
+CREATE TRIGGER ticket_search_delete
+    AFTER DELETE
+    ON tickets
+BEGIN
+    DELETE FROM ticket_search WHERE rowid = OLD.id;
+END;
@@ -0,0 +1,32 @@
+-- Tickets opened before categories were configurable stored the category's description rather than its title.
+-- Rewrite the descriptions of the built-in categories to their titles so that those tickets resolve to their
+-- category again.
+CREATE TEMPORARY TABLE category_titles
+(
+    description TEXT PRIMARY KEY,
+    title       TEXT NOT NULL
+);
+
+INSERT INTO category_titles (description, title)
+VALUES ('General support questions', 'general-support'),
+       ('Moderation support questions', 'mod-support'),
+       ('Mod/Admin support questions', 'staff-support'),
+       ('Admin support questions', 'admin-support'),
+       ('Owner support questions', 'owner-support'),
+       ('User support questions', 'user-support'),
+       ('General suggestion', 'general-suggestion'),
+       ('Mod suggestion', 'mod-suggestion'),
+       ('Mod/Admin suggestion', 'staff-suggestion'),
+       ('Admin suggestion', 'admin-suggestion'),
+       ('Owner suggestion', 'owner-suggestion'),
+       ('User suggestion', 'user-suggestion');
+
+UPDATE tickets
+SET category = (SELECT title FROM category_titles WHERE description = tickets.category)
+WHERE category IN (SELECT description FROM category_titles);
+
+UPDATE sla_escalations
+SET category = (SELECT title FROM category_titles WHERE description = sla_escalations.category)
+WHERE category IN (SELECT description FROM category_titles);
+
+DROP TABLE category_titles;
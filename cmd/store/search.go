@@ -0,0 +1,40 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// TicketSearchFilter narrows the tickets returned by Store.SearchTickets.
+type TicketSearchFilter struct {
+	GuildID snowflake.ID
+	// Query is the text to search for as a member typed it: every word must match, the last one also as the start
+	// of a longer word
+	Query string
+	// Scope keeps the tickets of the categories it allows, when set
+	Scope *CategoryScope
+	// Recent lists the newest matching tickets first rather than the best matches
+	Recent bool
+	Limit  int
+}
+
+// TicketSearchResult is a ticket matching a search, with an excerpt of the text which matched.
+type TicketSearchResult struct {
+	Ticket
+	// Snippet has the matching words in bold
+	Snippet string
+}
+
+// searchQuery turns what a member typed into a full-text query, quoting each word so that none is taken for query
+// syntax. It returns an empty query for text without words.
+func searchQuery(text string) string {
+	words := strings.Fields(text)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+	}
+	if len(words) > 0 {
+		words[len(words)-1] += "*"
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"slices"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// PingMode controls whom a guild's new and reopened tickets ping.
+type PingMode string
+
+const (
+	// PingAll pings every role and member responsible for the ticket's category
+	PingAll PingMode = "all"
+	// PingNone does not ping anyone; the support team has to watch the support channel instead
+	PingNone PingMode = "none"
+)
+
+// PingModes lists the valid ping modes.
+var PingModes = []PingMode{PingAll, PingNone}
+
+// GuildSettings are the settings a guild's admins manage themselves. Zero values mean the bot's configured
+// defaults apply.
+type GuildSettings struct {
+	GuildID snowflake.ID
+	// SupportChannelID is the support channel the bot created or adopted; it is kept up to date by the bot rather
+	// than set by admins
+	SupportChannelID    snowflake.ID
+	SupportChannelName  string
+	SupportChannelTopic string
+	LogChannelID        snowflake.ID
+	// StaffRoleIDs are roles treated as support team for every category, on top of the access policy
+	StaffRoleIDs []snowflake.ID
+	// DisabledCategories are the titles of configured categories the guild does not offer
+	DisabledCategories []string
+	PingMode           PingMode
+	// MaxOpenTickets limits how many tickets a member may have open at once; 0 means no limit
+	MaxOpenTickets int
+	UpdatedAt      time.Time
+}
+
+// CategoryEnabled reports whether the guild offers the category with the given title.
+func (s GuildSettings) CategoryEnabled(title string) bool {
+	return !slices.Contains(s.DisabledCategories, title)
+}
+
+// Pings reports whether new and reopened tickets ping their support team.
+func (s GuildSettings) Pings() bool {
+	return s.PingMode != PingNone
+}
@@ -0,0 +1,38 @@
+package store
+
+import (
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// SLALevel is how a ticket stands against one of its SLA deadlines.
+type SLALevel string
+
+const (
+	SLANearing  SLALevel = "nearing"
+	SLABreached SLALevel = "breached"
+)
+
+// SLAEscalation records that a ticket neared or breached one of its SLA deadlines. A ticket is escalated at most
+// once per target and level, and escalations at the breached level make up the breach history kept for reporting.
+type SLAEscalation struct {
+	ID       int64
+	TicketID int64
+	GuildID  snowflake.ID
+	Category string
+	// Target names the deadline, such as "first_response" or "resolution"
+	Target    string
+	Level     SLALevel
+	Deadline  time.Time
+	CreatedAt time.Time
+}
+
+// SLAEscalationFilter narrows the escalations returned by Store.ListSLAEscalations. Zero values are ignored.
+type SLAEscalationFilter struct {
+	GuildID  snowflake.ID
+	TicketID int64
+	Level    SLALevel
+	// Since only includes escalations made at or after the given time
+	Since time.Time
+}
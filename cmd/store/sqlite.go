@@ -0,0 +1,787 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+	_ "modernc.org/sqlite"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+)
+
+// ticketFields lists the stored ticket columns besides id, in the order ticketArgs and scanTicket use them.
+var ticketFields = []string{
+	"number", "guild_id", "channel_id", "thread_id", "message_id", "opener_id", "opener_name", "assignee_id",
+	"category", "subject", "content", "attachment_url", "status", "created_at", "updated_at", "closed_at", "closed_by",
+	"close_reason", "reopen_count", "follow_up_of", "answers", "priority",
+}
+
+// activityFields lists the ticket activity and inactivity columns, which are read alongside ticketFields but only
+// ever written by RecordTicketActivity and the inactivity setters.
+var activityFields = []string{
+	"last_user_activity_at", "last_staff_activity_at", "first_staff_response_at", "user_message_count",
+	"staff_message_count", "inactivity_stage", "inactivity_stage_at", "inactivity_paused",
+}
+
+var (
+	ticketColumns      = "id, " + strings.Join(ticketFields, ", ") + ", " + strings.Join(activityFields, ", ")
+	ticketPlaceholders = placeholders(len(ticketFields))
+)
+
+func ticketArgs(t *Ticket) []any {
+	return []any{
+		t.Number, t.GuildID, t.ChannelID, t.ThreadID, t.MessageID, t.OpenerID, t.OpenerName, t.AssigneeID,
+		t.Category, t.Subject, t.Content, t.AttachmentURL, t.Status, toUnix(t.CreatedAt), toUnix(t.UpdatedAt),
+		toNullUnix(t.ClosedAt), t.ClosedBy, t.CloseReason, t.ReopenCount, t.FollowUpOf, t.Answers, t.Priority,
+	}
+}
+
+// priorityOrder sorts tickets from the most to the least urgent.
+var priorityOrder = func() string {
+	var sb strings.Builder
+	sb.WriteString("CASE priority")
+	for _, p := range common.Priorities {
+		fmt.Fprintf(&sb, " WHEN '%s' THEN %d", p, -p.Rank())
+	}
+	fmt.Fprintf(&sb, " ELSE %d END", -common.PriorityNormal.Rank())
+	return sb.String()
+}()
+
+// SQLiteStore is a Store backed by an embedded SQLite database file.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (or creates) the SQLite database at path and brings its schema up to date.
+func NewSQLiteStore(ctx context.Context, path string) (*SQLiteStore, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := openSQLite(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err = migrate(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// NewReadOnlySQLiteStore opens the SQLite database at path without creating or migrating it. A database which does
+// not exist yet is stood in for by an empty one held in memory, and one whose schema is out of date is opened as it
+// is, with a warning, since reading it may fail.
+func NewReadOnlySQLiteStore(ctx context.Context, path string) (*SQLiteStore, error) {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("Ticket store does not exist yet, using an empty one", slog.String("path", path))
+		db, err := openSQLite(ctx, "file::memory:")
+		if err != nil {
+			return nil, err
+		}
+		if err = migrate(ctx, db); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		return &SQLiteStore{db: db}, nil
+	}
+	db, err := openSQLite(ctx, "file:"+path+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	pending, err := pendingMigrations(ctx, db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	if pending > 0 {
+		slog.Warn("Ticket store schema is out of date; reading it may fail", slog.Int("pending_migrations", pending))
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func openSQLite(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to open sqlite database")
+	}
+	// SQLite only supports a single writer; serialising connections avoids SQLITE_BUSY under load.
+	db.SetMaxOpenConns(1)
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, errors.WithMessage(err, "failed to connect to sqlite database")
+	}
+	return db, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) NextTicketNumber(ctx context.Context, guildID snowflake.ID) (int64, error) {
+	var number int64
+	if err := s.db.QueryRowContext(ctx,
+		`INSERT INTO guild_ticket_counters (guild_id, last_number) VALUES (?, 1)
+		ON CONFLICT (guild_id) DO UPDATE SET last_number = last_number + 1
+		RETURNING last_number`,
+		guildID,
+	).Scan(&number); err != nil {
+		return 0, errors.WithMessage(err, "failed to allocate ticket number")
+	}
+	return number, nil
+}
+
+func (s *SQLiteStore) CreateTicket(ctx context.Context, t *Ticket, maxOpen int) error {
+	now := time.Now().UTC()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	t.UpdatedAt = now
+	if t.Status == "" {
+		t.Status = TicketStatusOpen
+	}
+	if t.Priority == "" {
+		t.Priority = common.PriorityNormal
+	}
+	// the limit is checked by the insert itself, so that concurrent tickets cannot both slip under it
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO tickets (`+strings.Join(ticketFields, ", ")+`) SELECT `+ticketPlaceholders+`
+		WHERE ? <= 0 OR (SELECT COUNT(*) FROM tickets WHERE guild_id = ? AND opener_id = ? AND status = ?) < ?`,
+		append(ticketArgs(t), maxOpen, t.GuildID, t.OpenerID, TicketStatusOpen, maxOpen)...,
+	)
+	if err != nil {
+		return errors.WithMessage(err, "failed to insert ticket")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTicketLimit
+	}
+	if t.ID, err = res.LastInsertId(); err != nil {
+		return errors.WithMessage(err, "failed to read ticket id")
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AssignTicket(ctx context.Context, ticketID int64, assigneeID, previousID snowflake.ID) error {
+	return s.updateTicket(ctx, ticketID, "assign ticket",
+		`UPDATE tickets SET assignee_id = ?, updated_at = ? WHERE id = ? AND assignee_id = ?`,
+		assigneeID, toUnix(time.Now().UTC()), ticketID, previousID,
+	)
+}
+
+func (s *SQLiteStore) SetTicketPriority(ctx context.Context, ticketID int64, priority common.Priority) error {
+	return s.updateTicket(ctx, ticketID, "set ticket priority",
+		`UPDATE tickets SET priority = ?, updated_at = ? WHERE id = ?`,
+		priority, toUnix(time.Now().UTC()), ticketID,
+	)
+}
+
+func (s *SQLiteStore) CloseTicket(
+	ctx context.Context, ticketID int64, closedAt time.Time, closedBy snowflake.ID, reason string,
+) error {
+	return s.updateTicket(ctx, ticketID, "close ticket",
+		`UPDATE tickets SET status = ?, closed_at = ?, closed_by = ?, close_reason = ?, updated_at = ?
+		WHERE id = ? AND status = ?`,
+		TicketStatusClosed, toUnix(closedAt), closedBy, reason, toUnix(time.Now().UTC()), ticketID, TicketStatusOpen,
+	)
+}
+
+func (s *SQLiteStore) UndoCloseTicket(ctx context.Context, ticketID int64, closedAt time.Time) error {
+	return s.updateTicket(ctx, ticketID, "undo ticket closure",
+		`UPDATE tickets SET status = ?, closed_at = NULL, closed_by = 0, close_reason = '', updated_at = ?
+		WHERE id = ? AND status = ? AND closed_at = ?`,
+		TicketStatusOpen, toUnix(time.Now().UTC()), ticketID, TicketStatusClosed, toUnix(closedAt),
+	)
+}
+
+func (s *SQLiteStore) ReopenTicket(ctx context.Context, ticketID int64) error {
+	return s.updateTicket(ctx, ticketID, "reopen ticket",
+		`UPDATE tickets SET status = ?, closed_at = NULL, closed_by = 0, close_reason = '',
+			reopen_count = reopen_count + 1, updated_at = ?
+		WHERE id = ? AND status = ?`,
+		TicketStatusOpen, toUnix(time.Now().UTC()), ticketID, TicketStatusClosed,
+	)
+}
+
+// updateTicket runs an update of a single ticket. When no row is updated, it tells a missing ticket (ErrNotFound)
+// apart from one which no longer meets the update's conditions (ErrConflict).
+func (s *SQLiteStore) updateTicket(ctx context.Context, ticketID int64, action, query string, args ...any) error {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return errors.WithMessage(err, "failed to "+action)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	var exists bool
+	if err = s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM tickets WHERE id = ?)`, ticketID,
+	).Scan(&exists); err != nil {
+		return errors.WithMessage(err, "failed to "+action)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+func (s *SQLiteStore) GetTicket(ctx context.Context, id int64) (*Ticket, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+ticketColumns+` FROM tickets WHERE id = ?`, id)
+	return scanTicket(row)
+}
+
+func (s *SQLiteStore) GetTicketByThread(ctx context.Context, threadID snowflake.ID) (*Ticket, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+ticketColumns+` FROM tickets WHERE thread_id = ?`, threadID)
+	return scanTicket(row)
+}
+
+func (s *SQLiteStore) GetTicketByNumber(ctx context.Context, guildID snowflake.ID, number int64) (*Ticket, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+ticketColumns+` FROM tickets WHERE guild_id = ? AND number = ?`,
+		guildID, number,
+	)
+	return scanTicket(row)
+}
+
+func (s *SQLiteStore) ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error) {
+	where, args := ticketFilterClauses(filter)
+	query := `SELECT ` + ticketColumns + ` FROM tickets` + where + " ORDER BY "
+	if filter.ByPriority {
+		query += priorityOrder + ", "
+	}
+	// the id breaks ties, so that listing a page at a time neither repeats nor skips tickets
+	query += "created_at DESC, id DESC"
+	if filter.Limit > 0 || filter.Offset > 0 {
+		query += " LIMIT ? OFFSET ?"
+		limit := filter.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		args = append(args, limit, filter.Offset)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to list tickets")
+	}
+	defer rows.Close()
+	var tickets []Ticket
+	for rows.Next() {
+		t, err := scanTicket(rows)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, *t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "failed to iterate tickets")
+	}
+	return tickets, nil
+}
+
+func (s *SQLiteStore) CountTickets(ctx context.Context, filter TicketFilter) (int, error) {
+	where, args := ticketFilterClauses(filter)
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tickets`+where, args...).Scan(&n); err != nil {
+		return 0, errors.WithMessage(err, "failed to count tickets")
+	}
+	return n, nil
+}
+
+// ticketFilterClauses builds the WHERE clause, if any, selecting the tickets matching the filter.
+func ticketFilterClauses(filter TicketFilter) (string, []any) {
+	var (
+		clauses []string
+		args    []any
+	)
+	if filter.GuildID != 0 {
+		clauses = append(clauses, "guild_id = ?")
+		args = append(args, filter.GuildID)
+	}
+	if filter.OpenerID != 0 {
+		clauses = append(clauses, "opener_id = ?")
+		args = append(args, filter.OpenerID)
+	}
+	if filter.AssigneeID != 0 {
+		clauses = append(clauses, "assignee_id = ?")
+		args = append(args, filter.AssigneeID)
+	}
+	if filter.Category != "" {
+		clauses = append(clauses, "category = ?")
+		args = append(args, filter.Category)
+	}
+	if filter.Status != "" {
+		clauses = append(clauses, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Priority != "" {
+		clauses = append(clauses, "priority = ?")
+		args = append(args, filter.Priority)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		clauses = append(clauses, "created_at < ?")
+		args = append(args, toUnix(filter.CreatedBefore))
+	}
+	if filter.Scope != nil {
+		clause, scopeArgs := filter.Scope.clause("category")
+		clauses = append(clauses, clause)
+		args = append(args, scopeArgs...)
+	}
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(clauses, " AND "), args
+}
+
+// clause builds the condition keeping the tickets whose category, held in the given column, the scope allows.
+func (c *CategoryScope) clause(column string) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if len(c.Allowed) > 0 {
+		conditions = append(conditions, column+" IN ("+placeholders(len(c.Allowed))+")")
+		for _, title := range c.Allowed {
+			args = append(args, title)
+		}
+	}
+	if c.AllowUnknown {
+		condition := "TRUE"
+		if len(c.Known) > 0 {
+			condition = column + " NOT IN (" + placeholders(len(c.Known)) + ")"
+			for _, title := range c.Known {
+				args = append(args, title)
+			}
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(conditions) == 0 {
+		return "FALSE", nil
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func (s *SQLiteStore) RecordTicketActivity(ctx context.Context, ticketID int64, at time.Time, staff bool) error {
+	query := `UPDATE tickets SET last_user_activity_at = ?, user_message_count = user_message_count + 1,
+			inactivity_stage = 0, inactivity_stage_at = NULL
+		WHERE id = ?`
+	if staff {
+		query = `UPDATE tickets SET last_staff_activity_at = ?1,
+			first_staff_response_at = COALESCE(first_staff_response_at, ?1),
+			staff_message_count = staff_message_count + 1,
+			inactivity_stage = 0, inactivity_stage_at = NULL
+		WHERE id = ?2`
+	}
+	res, err := s.db.ExecContext(ctx, query, toUnix(at), ticketID)
+	if err != nil {
+		return errors.WithMessage(err, "failed to record ticket activity")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) SetTicketInactivity(ctx context.Context, ticketID int64, stage int, at time.Time) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE tickets SET inactivity_stage = ?, inactivity_stage_at = ? WHERE id = ?`,
+		stage, toUnix(at), ticketID,
+	)
+	if err != nil {
+		return errors.WithMessage(err, "failed to record ticket inactivity")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) SetTicketInactivityPaused(ctx context.Context, ticketID int64, paused bool) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE tickets SET inactivity_paused = ? WHERE id = ?`, paused, ticketID)
+	if err != nil {
+		return errors.WithMessage(err, "failed to pause ticket inactivity")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AddTicketEvent(ctx context.Context, e *TicketEvent) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO ticket_events (ticket_id, kind, actor_id, target_id, detail, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		e.TicketID, e.Kind, e.ActorID, e.TargetID, e.Detail, toUnix(e.CreatedAt),
+	)
+	if err != nil {
+		return errors.WithMessage(err, "failed to insert ticket event")
+	}
+	if e.ID, err = res.LastInsertId(); err != nil {
+		return errors.WithMessage(err, "failed to read ticket event id")
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListTicketEvents(ctx context.Context, ticketID int64) ([]TicketEvent, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, ticket_id, kind, actor_id, target_id, detail, created_at
+		FROM ticket_events WHERE ticket_id = ? ORDER BY created_at, id`,
+		ticketID,
+	)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to list ticket events")
+	}
+	defer rows.Close()
+	var events []TicketEvent
+	for rows.Next() {
+		var (
+			e         TicketEvent
+			createdAt int64
+		)
+		if err = rows.Scan(&e.ID, &e.TicketID, &e.Kind, &e.ActorID, &e.TargetID, &e.Detail, &createdAt); err != nil {
+			return nil, errors.WithMessage(err, "failed to scan ticket event")
+		}
+		e.CreatedAt = fromUnix(createdAt)
+		events = append(events, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "failed to iterate ticket events")
+	}
+	return events, nil
+}
+
+func (s *SQLiteStore) AddSLAEscalation(ctx context.Context, e *SLAEscalation) (bool, error) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO sla_escalations (ticket_id, guild_id, category, target, level, deadline, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (ticket_id, target, level) DO NOTHING`,
+		e.TicketID, e.GuildID, e.Category, e.Target, e.Level, toUnix(e.Deadline), toUnix(e.CreatedAt),
+	)
+	if err != nil {
+		return false, errors.WithMessage(err, "failed to insert SLA escalation")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.WithMessage(err, "failed to check SLA escalation")
+	}
+	if n == 0 {
+		return false, nil
+	}
+	if e.ID, err = res.LastInsertId(); err != nil {
+		return false, errors.WithMessage(err, "failed to read SLA escalation id")
+	}
+	return true, nil
+}
+
+func (s *SQLiteStore) ListSLAEscalations(ctx context.Context, filter SLAEscalationFilter) ([]SLAEscalation, error) {
+	var (
+		clauses []string
+		args    []any
+	)
+	if filter.GuildID != 0 {
+		clauses = append(clauses, "guild_id = ?")
+		args = append(args, filter.GuildID)
+	}
+	if filter.TicketID != 0 {
+		clauses = append(clauses, "ticket_id = ?")
+		args = append(args, filter.TicketID)
+	}
+	if filter.Level != "" {
+		clauses = append(clauses, "level = ?")
+		args = append(args, filter.Level)
+	}
+	if !filter.Since.IsZero() {
+		clauses = append(clauses, "created_at >= ?")
+		args = append(args, toUnix(filter.Since))
+	}
+	query := `SELECT id, ticket_id, guild_id, category, target, level, deadline, created_at FROM sla_escalations`
+	if len(clauses) > 0 {
+		query += " WHERE " + strings.Join(clauses, " AND ")
+	}
+	query += " ORDER BY created_at, id"
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to list SLA escalations")
+	}
+	defer rows.Close()
+	var escalations []SLAEscalation
+	for rows.Next() {
+		var (
+			e                   SLAEscalation
+			deadline, createdAt int64
+		)
+		if err = rows.Scan(
+			&e.ID, &e.TicketID, &e.GuildID, &e.Category, &e.Target, &e.Level, &deadline, &createdAt,
+		); err != nil {
+			return nil, errors.WithMessage(err, "failed to scan SLA escalation")
+		}
+		e.Deadline = fromUnix(deadline)
+		e.CreatedAt = fromUnix(createdAt)
+		escalations = append(escalations, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "failed to iterate SLA escalations")
+	}
+	return escalations, nil
+}
+
+func (s *SQLiteStore) IndexTicketMessage(ctx context.Context, ticketID int64, content string) error {
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE ticket_search SET messages = messages || char(10) || ? WHERE rowid = ?`,
+		content, ticketID,
+	); err != nil {
+		return errors.WithMessage(err, "failed to index ticket message")
+	}
+	return nil
+}
+
+func (s *SQLiteStore) IndexTicketMessages(ctx context.Context, ticketID int64, contents []string) error {
+	if _, err := s.db.ExecContext(ctx,
+		`UPDATE ticket_search SET messages = ? WHERE rowid = ?`,
+		strings.Join(contents, "\n"), ticketID,
+	); err != nil {
+		return errors.WithMessage(err, "failed to index ticket messages")
+	}
+	return nil
+}
+
+// searchColumns are the ticket columns qualified by their table, as the search index shares some of their names.
+var searchColumns = "tickets." + strings.ReplaceAll(ticketColumns, ", ", ", tickets.")
+
+func (s *SQLiteStore) SearchTickets(ctx context.Context, filter TicketSearchFilter) ([]TicketSearchResult, error) {
+	query := searchQuery(filter.Query)
+	if query == "" {
+		return nil, nil
+	}
+	// matches in the subject weigh the most and matches in the messages the least
+	q := `SELECT ` + searchColumns + `, snippet(ticket_search, -1, '**', '**', '…', 16)
+		FROM ticket_search JOIN tickets ON tickets.id = ticket_search.rowid
+		WHERE ticket_search MATCH ? AND tickets.guild_id = ?`
+	args := []any{query, filter.GuildID}
+	if filter.Scope != nil {
+		clause, scopeArgs := filter.Scope.clause("tickets.category")
+		q += " AND " + clause
+		args = append(args, scopeArgs...)
+	}
+	q += " ORDER BY "
+	if filter.Recent {
+		q += "tickets.created_at DESC"
+	} else {
+		q += "bm25(ticket_search, 10.0, 5.0, 2.0, 1.0)"
+	}
+	if filter.Limit > 0 {
+		q += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to search tickets")
+	}
+	defer rows.Close()
+	var results []TicketSearchResult
+	for rows.Next() {
+		var snippet string
+		t, err := scanTicket(extraScanner{row: rows, extra: []any{&snippet}})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, TicketSearchResult{Ticket: *t, Snippet: snippet})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "failed to iterate ticket search results")
+	}
+	return results, nil
+}
+
+func (s *SQLiteStore) GetGuildSettings(ctx context.Context, guildID snowflake.ID) (*GuildSettings, error) {
+	var (
+		settings                         = GuildSettings{GuildID: guildID}
+		staffRoleIDs, disabledCategories string
+		updatedAt                        int64
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT support_channel_id, support_channel_name, support_channel_topic, log_channel_id, staff_role_ids,
+			disabled_categories, ping_mode, max_open_tickets, updated_at
+		FROM guild_settings WHERE guild_id = ?`,
+		guildID,
+	).Scan(
+		&settings.SupportChannelID, &settings.SupportChannelName, &settings.SupportChannelTopic,
+		&settings.LogChannelID, &staffRoleIDs, &disabledCategories, &settings.PingMode, &settings.MaxOpenTickets,
+		&updatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &settings, nil
+	} else if err != nil {
+		return nil, errors.WithMessage(err, "failed to get guild settings")
+	}
+	if err = json.Unmarshal([]byte(staffRoleIDs), &settings.StaffRoleIDs); err != nil {
+		return nil, errors.WithMessage(err, "failed to decode staff roles")
+	}
+	if err = json.Unmarshal([]byte(disabledCategories), &settings.DisabledCategories); err != nil {
+		return nil, errors.WithMessage(err, "failed to decode disabled categories")
+	}
+	settings.UpdatedAt = fromUnix(updatedAt)
+	return &settings, nil
+}
+
+func (s *SQLiteStore) SaveGuildSettings(ctx context.Context, settings *GuildSettings) error {
+	settings.UpdatedAt = time.Now().UTC()
+	staffRoleIDs, err := json.Marshal(nonNil(settings.StaffRoleIDs))
+	if err != nil {
+		return errors.WithMessage(err, "failed to encode staff roles")
+	}
+	disabledCategories, err := json.Marshal(nonNil(settings.DisabledCategories))
+	if err != nil {
+		return errors.WithMessage(err, "failed to encode disabled categories")
+	}
+	if _, err = s.db.ExecContext(ctx,
+		`INSERT INTO guild_settings (guild_id, support_channel_name, support_channel_topic, log_channel_id,
+			staff_role_ids, disabled_categories, ping_mode, max_open_tickets, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (guild_id) DO UPDATE SET
+			support_channel_name = excluded.support_channel_name,
+			support_channel_topic = excluded.support_channel_topic,
+			log_channel_id = excluded.log_channel_id,
+			staff_role_ids = excluded.staff_role_ids,
+			disabled_categories = excluded.disabled_categories,
+			ping_mode = excluded.ping_mode,
+			max_open_tickets = excluded.max_open_tickets,
+			updated_at = excluded.updated_at`,
+		settings.GuildID, settings.SupportChannelName, settings.SupportChannelTopic, settings.LogChannelID,
+		string(staffRoleIDs), string(disabledCategories), settings.PingMode, settings.MaxOpenTickets,
+		toUnix(settings.UpdatedAt),
+	); err != nil {
+		return errors.WithMessage(err, "failed to save guild settings")
+	}
+	return nil
+}
+
+func (s *SQLiteStore) SetSupportChannel(ctx context.Context, guildID snowflake.ID, channelID snowflake.ID) error {
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO guild_settings (guild_id, support_channel_id, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (guild_id) DO UPDATE SET support_channel_id = excluded.support_channel_id`,
+		guildID, channelID, toUnix(time.Now().UTC()),
+	); err != nil {
+		return errors.WithMessage(err, "failed to store support channel")
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteGuildSettings(ctx context.Context, guildID snowflake.ID) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM guild_settings WHERE guild_id = ?`, guildID); err != nil {
+		return errors.WithMessage(err, "failed to delete guild settings")
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteGuild(ctx context.Context, guildID snowflake.ID) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.WithMessage(err, "failed to begin transaction")
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	// Ticket events and SLA escalations are removed along with their tickets by their foreign keys, and the tickets'
+	// search index entries by a trigger.
+	for _, table := range []string{"tickets", "guild_ticket_counters", "guild_settings"} {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE guild_id = ?`, guildID); err != nil {
+			return errors.WithMessagef(err, "failed to delete guild from %s", table)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.WithMessage(err, "failed to commit guild deletion")
+	}
+	return nil
+}
+
+// nonNil returns an empty slice for nil, so that lists are always stored as JSON arrays.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// extraScanner scans columns selected after the ticket columns into extra.
+type extraScanner struct {
+	row   rowScanner
+	extra []any
+}
+
+func (s extraScanner) Scan(dest ...any) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
+
+func scanTicket(row rowScanner) (*Ticket, error) {
+	var (
+		t                    Ticket
+		createdAt, updatedAt int64
+		closedAt             sql.NullInt64
+		lastUserActivityAt   sql.NullInt64
+		lastStaffActivityAt  sql.NullInt64
+		firstStaffResponseAt sql.NullInt64
+		inactivityStageAt    sql.NullInt64
+	)
+	err := row.Scan(
+		&t.ID, &t.Number, &t.GuildID, &t.ChannelID, &t.ThreadID, &t.MessageID, &t.OpenerID, &t.OpenerName,
+		&t.AssigneeID, &t.Category, &t.Subject, &t.Content, &t.AttachmentURL, &t.Status, &createdAt, &updatedAt,
+		&closedAt, &t.ClosedBy, &t.CloseReason, &t.ReopenCount, &t.FollowUpOf, &t.Answers, &t.Priority,
+		&lastUserActivityAt, &lastStaffActivityAt, &firstStaffResponseAt, &t.UserMessageCount, &t.StaffMessageCount,
+		&t.InactivityStage, &inactivityStageAt, &t.InactivityPaused,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, errors.WithMessage(err, "failed to scan ticket")
+	}
+	t.CreatedAt = fromUnix(createdAt)
+	t.UpdatedAt = fromUnix(updatedAt)
+	t.ClosedAt = fromNullUnix(closedAt)
+	t.LastUserActivityAt = fromNullUnix(lastUserActivityAt)
+	t.LastStaffActivityAt = fromNullUnix(lastStaffActivityAt)
+	t.FirstStaffResponseAt = fromNullUnix(firstStaffResponseAt)
+	t.InactivityStageAt = fromNullUnix(inactivityStageAt)
+	return &t, nil
+}
+
+// Timestamps are stored as unix milliseconds in UTC.
+
+func toUnix(t time.Time) int64 {
+	return t.UnixMilli()
+}
+
+func toNullUnix(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixMilli(), Valid: true}
+}
+
+func fromUnix(ms int64) time.Time {
+	return time.UnixMilli(ms).UTC()
+}
+
+func fromNullUnix(ms sql.NullInt64) *time.Time {
+	if !ms.Valid {
+		return nil
+	}
+	t := fromUnix(ms.Int64)
+	return &t
+}
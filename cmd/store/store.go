@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+)
+
+// Supported storage drivers
+const (
+	DriverSQLite = "sqlite"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a ticket has changed since it was read such that the requested change no longer
+	// applies, such as claiming a ticket someone else has just claimed.
+	ErrConflict = errors.New("ticket changed concurrently")
+	// ErrTicketLimit is returned when a ticket's opener already has as many open tickets as they may have.
+	ErrTicketLimit = errors.New("open ticket limit reached")
+)
+
+// TicketFilter narrows the tickets returned by Store.ListTickets. Zero values are ignored.
+type TicketFilter struct {
+	GuildID    snowflake.ID
+	OpenerID   snowflake.ID
+	AssigneeID snowflake.ID
+	Category   string
+	Status     TicketStatus
+	Priority   common.Priority
+	// CreatedBefore keeps the tickets opened before the given time
+	CreatedBefore time.Time
+	// Scope keeps the tickets of the categories it allows, when set
+	Scope *CategoryScope
+	// ByPriority lists the most urgent tickets first; tickets are otherwise listed newest first
+	ByPriority bool
+	Limit      int
+	// Offset skips as many of the matching tickets, for listing them a page at a time
+	Offset int
+}
+
+// CategoryScope limits tickets to some categories, such as those a member of staff supports: the categories in
+// Allowed, along with any category not in Known if AllowUnknown is set, such as those since removed from the
+// configuration.
+type CategoryScope struct {
+	Allowed      []string
+	Known        []string
+	AllowUnknown bool
+}
+
+// Store persists tickets across restarts. Implementations must be safe for concurrent use.
+type Store interface {
+	// NextTicketNumber atomically allocates the next sequential ticket number for the guild.
+	// Numbers are never reused, even if the ticket they were allocated for is never stored.
+	NextTicketNumber(ctx context.Context, guildID snowflake.ID) (int64, error)
+	// CreateTicket stores a new ticket. Unless maxOpen is 0, it returns ErrTicketLimit instead if the ticket's opener
+	// already has maxOpen open tickets in the guild.
+	CreateTicket(ctx context.Context, t *Ticket, maxOpen int) error
+	// AssignTicket changes the ticket's assignee, provided it is still assigned to previousID (0 for nobody), and
+	// returns ErrConflict otherwise.
+	AssignTicket(ctx context.Context, ticketID int64, assigneeID, previousID snowflake.ID) error
+	SetTicketPriority(ctx context.Context, ticketID int64, priority common.Priority) error
+	// CloseTicket marks an open ticket as closed, and returns ErrConflict if it is already closed.
+	CloseTicket(ctx context.Context, ticketID int64, closedAt time.Time, closedBy snowflake.ID, reason string) error
+	// UndoCloseTicket marks a ticket closed at closedAt as open again without counting a reopening, for a closure
+	// which could not be carried out, and returns ErrConflict if the ticket has changed since.
+	UndoCloseTicket(ctx context.Context, ticketID int64, closedAt time.Time) error
+	// ReopenTicket marks a closed ticket as open again and counts the reopening, and returns ErrConflict if it is
+	// already open.
+	ReopenTicket(ctx context.Context, ticketID int64) error
+	GetTicket(ctx context.Context, id int64) (*Ticket, error)
+	GetTicketByThread(ctx context.Context, threadID snowflake.ID) (*Ticket, error)
+	GetTicketByNumber(ctx context.Context, guildID snowflake.ID, number int64) (*Ticket, error)
+	ListTickets(ctx context.Context, filter TicketFilter) ([]Ticket, error)
+	// CountTickets counts the tickets matching the filter, ignoring its limit and offset.
+	CountTickets(ctx context.Context, filter TicketFilter) (int, error)
+	// RecordTicketActivity counts a message posted in the ticket's thread at the given time, either by a member
+	// of staff or by anyone else, and updates the ticket's activity timestamps accordingly. The ticket's inactivity
+	// stage is reset.
+	RecordTicketActivity(ctx context.Context, ticketID int64, at time.Time, staff bool) error
+	// SetTicketInactivity records the inactivity step taken for the ticket at the given time; step 0 restarts the
+	// inactivity clock.
+	SetTicketInactivity(ctx context.Context, ticketID int64, stage int, at time.Time) error
+	// SetTicketInactivityPaused pauses or resumes the ticket's inactivity reminders and automatic closure.
+	SetTicketInactivityPaused(ctx context.Context, ticketID int64, paused bool) error
+	AddTicketEvent(ctx context.Context, e *TicketEvent) error
+	ListTicketEvents(ctx context.Context, ticketID int64) ([]TicketEvent, error)
+	// AddSLAEscalation records the escalation unless the ticket was already escalated for the same target and
+	// level, and reports whether it was recorded.
+	AddSLAEscalation(ctx context.Context, e *SLAEscalation) (bool, error)
+	// ListSLAEscalations returns the matching escalations, oldest first.
+	ListSLAEscalations(ctx context.Context, filter SLAEscalationFilter) ([]SLAEscalation, error)
+	// IndexTicketMessage adds a message posted in the ticket's thread to the ticket's searchable text. The ticket's
+	// own subject, content and category are indexed along with the ticket itself.
+	IndexTicketMessage(ctx context.Context, ticketID int64, content string) error
+	// IndexTicketMessages replaces the ticket's searchable messages, such as with its whole thread once it is closed.
+	IndexTicketMessages(ctx context.Context, ticketID int64, contents []string) error
+	// SearchTickets returns the tickets whose text matches the query, best matches first unless the filter asks for
+	// the most recent ones.
+	SearchTickets(ctx context.Context, filter TicketSearchFilter) ([]TicketSearchResult, error)
+	// GetGuildSettings returns the guild's stored settings, or zero settings for the guild if none are stored.
+	GetGuildSettings(ctx context.Context, guildID snowflake.ID) (*GuildSettings, error)
+	// SaveGuildSettings stores the settings the guild's admins manage; the support channel is left as it is.
+	SaveGuildSettings(ctx context.Context, s *GuildSettings) error
+	// SetSupportChannel remembers the guild's support channel without touching its other settings.
+	SetSupportChannel(ctx context.Context, guildID snowflake.ID, channelID snowflake.ID) error
+	DeleteGuildSettings(ctx context.Context, guildID snowflake.ID) error
+	// DeleteGuild removes everything stored for the guild: its tickets with their events and escalations, its
+	// ticket counter and its settings.
+	DeleteGuild(ctx context.Context, guildID snowflake.ID) error
+	Close() error
+}
+
+// Open returns a Store for the given driver, creating and migrating the underlying database if required.
+func Open(ctx context.Context, driver, path string) (Store, error) {
+	switch driver {
+	case DriverSQLite, "":
+		return NewSQLiteStore(ctx, path)
+	default:
+		return nil, errors.Errorf("unsupported store driver %q", driver)
+	}
+}
+
+// OpenReadOnly returns a Store for the given driver which only reads the existing database: the database is neither
+// created nor migrated, and writes fail.
+func OpenReadOnly(ctx context.Context, driver, path string) (Store, error) {
+	switch driver {
+	case DriverSQLite, "":
+		return NewReadOnlySQLiteStore(ctx, path)
+	default:
+		return nil, errors.Errorf("unsupported store driver %q", driver)
+	}
+}
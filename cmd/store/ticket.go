@@ -0,0 +1,158 @@
+package store
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+)
+
+type TicketStatus string
+
+const (
+	TicketStatusOpen   TicketStatus = "open"
+	TicketStatusClosed TicketStatus = "closed"
+)
+
+// Ticket is the persisted record of a single support ticket and the thread that hosts it.
+// MessageID is the message in the thread holding the ticket content and its controls, and
+// FollowUpOf is the number of the earlier ticket this one follows up on, if any. ClosedBy is 0 if the bot closed the
+// ticket itself.
+//
+// A ticket is written whole only when it is created. Afterwards each action updates only the columns it changes,
+// such as Store.AssignTicket or Store.CloseTicket, so that a stale copy of the ticket cannot roll back another
+// action. The activity fields are maintained by Store.RecordTicketActivity from messages posted in the thread.
+type Ticket struct {
+	ID            int64
+	Number        int64
+	GuildID       snowflake.ID
+	ChannelID     snowflake.ID
+	ThreadID      snowflake.ID
+	MessageID     snowflake.ID
+	OpenerID      snowflake.ID
+	OpenerName    string
+	AssigneeID    snowflake.ID
+	Category      string
+	Subject       string
+	Content       string
+	AttachmentURL string
+	Status        TicketStatus
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	ClosedAt      *time.Time
+	ClosedBy      snowflake.ID
+	CloseReason   string
+	ReopenCount   int
+	FollowUpOf    int64
+	Answers       TicketAnswers
+	// Priority is set by the support team during triage; new tickets are normal
+	Priority common.Priority
+
+	LastUserActivityAt   *time.Time
+	LastStaffActivityAt  *time.Time
+	FirstStaffResponseAt *time.Time
+	UserMessageCount     int
+	StaffMessageCount    int
+
+	// InactivityStage is the last inactivity step taken for the ticket since its last message, and
+	// InactivityStageAt when it was taken or the inactivity clock was last restarted. Both are maintained by
+	// Store.SetTicketInactivity and reset by Store.RecordTicketActivity.
+	InactivityStage   int
+	InactivityStageAt *time.Time
+	// InactivityPaused stops inactivity reminders and automatic closure, e.g. while awaiting external information
+	InactivityPaused bool
+}
+
+// IsOpen reports whether the ticket is still awaiting resolution.
+func (t Ticket) IsOpen() bool {
+	return t.Status == TicketStatusOpen
+}
+
+// FirstResponseTime returns how long the ticket waited for its first message from staff, if it has had one.
+func (t Ticket) FirstResponseTime() (time.Duration, bool) {
+	if t.FirstStaffResponseAt == nil {
+		return 0, false
+	}
+	return t.FirstStaffResponseAt.Sub(t.CreatedAt), true
+}
+
+// LastActivity returns the time of the most recent message in the ticket, or its creation time if there is none.
+func (t Ticket) LastActivity() time.Time {
+	last := t.CreatedAt
+	for _, at := range []*time.Time{t.LastUserActivityAt, t.LastStaffActivityAt} {
+		if at != nil && at.After(last) {
+			last = *at
+		}
+	}
+	return last
+}
+
+// TicketAnswer is the answer to one of a category's extra questions, given when the ticket was created.
+type TicketAnswer struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// TicketAnswers are stored as a JSON array.
+type TicketAnswers []TicketAnswer
+
+func (a TicketAnswers) Value() (driver.Value, error) {
+	if a == nil {
+		a = TicketAnswers{}
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (a *TicketAnswers) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	case []byte:
+		return json.Unmarshal(v, a)
+	case nil:
+		*a = nil
+		return nil
+	default:
+		return errors.Errorf("cannot scan %T into ticket answers", src)
+	}
+}
+
+type TicketEventKind string
+
+const (
+	TicketEventCreated   TicketEventKind = "created"
+	TicketEventClosed    TicketEventKind = "closed"
+	TicketEventReopened  TicketEventKind = "reopened"
+	TicketEventClaimed   TicketEventKind = "claimed"
+	TicketEventUnclaimed TicketEventKind = "unclaimed"
+	TicketEventAssigned  TicketEventKind = "assigned"
+	// TicketEventReminded records an inactivity reminder; its detail names the step
+	TicketEventReminded TicketEventKind = "reminded"
+	TicketEventPaused   TicketEventKind = "paused"
+	TicketEventResumed  TicketEventKind = "resumed"
+	// TicketEventEscalated records an SLA escalation; its detail names the deadline and how the ticket stands
+	TicketEventEscalated TicketEventKind = "escalated"
+	// TicketEventPrioritised records a priority change; its detail names the previous priority
+	TicketEventPrioritised TicketEventKind = "prioritised"
+)
+
+// TicketEvent is a single entry in a ticket's history.
+type TicketEvent struct {
+	ID       int64
+	TicketID int64
+	Kind     TicketEventKind
+	// ActorID is the member who caused the event, or 0 for the bot itself
+	ActorID snowflake.ID
+	// TargetID is the member the event concerns, such as the new assignee
+	TargetID  snowflake.ID
+	Detail    string
+	CreatedAt time.Time
+}
@@ -11,6 +11,24 @@ import (
 //go:embed ticket.gomd
 var TicketTemplate string
 
+//go:embed ticket-closed.gomd
+var TicketClosedTemplate string
+
+//go:embed ticket-reopened.gomd
+var TicketReopenedTemplate string
+
+//go:embed ticket-inactive.gomd
+var TicketInactiveTemplate string
+
+//go:embed ticket-escalated.gomd
+var TicketEscalatedTemplate string
+
+//go:embed ticket-sla.gomd
+var TicketSLATemplate string
+
+//go:embed ticket-settings.gomd
+var TicketSettingsTemplate string
+
 //go:embed help.gomd
 var HelpTemplate string
 
@@ -18,12 +36,83 @@ var HelpTemplate string
 var HelpEphemeralTemplate string
 
 type TicketData struct {
+	Number        int64
 	Category      string
 	Username      string
 	Subject       string
 	Content       string
+	Answers       []TicketAnswer
 	Moderators    []string
+	Members       []string
 	AttachmentURL string
+	FollowUpOf    int64
+	Assignee      string
+	// RepliesFrom is when support opens again if the ticket was opened outside support hours, otherwise 0
+	RepliesFrom int64
+}
+
+type TicketAnswer struct {
+	Label string
+	Value string
+}
+
+type TicketClosedData struct {
+	Number   int64
+	ClosedBy string
+	Reason   string
+	OpenedAt int64
+	ClosedAt int64
+}
+
+type TicketReopenedData struct {
+	Number      int64
+	ReopenedBy  string
+	ReopenCount int
+	Moderators  []string
+	Members     []string
+}
+
+type TicketInactiveData struct {
+	Number   int64
+	OpenerID string
+	// ClosesAt is when the ticket will be closed as inactive, or 0 if it will not be
+	ClosesAt int64
+	Final    bool
+}
+
+type TicketEscalatedData struct {
+	Number int64
+	// Target names the missed or approaching deadline, such as "first response"
+	Target     string
+	Deadline   int64
+	Breached   bool
+	Moderators []string
+	Members    []string
+}
+
+type TicketSLAData struct {
+	Number  int64
+	Targets []SLATarget
+}
+
+type SLATarget struct {
+	Target   string
+	Deadline int64
+	// MetAt is when the target was met, or 0 if it has not been yet
+	MetAt  int64
+	Status string
+	Flag   string
+}
+
+type TicketSettingsData struct {
+	SupportChannelName  string
+	SupportChannelTopic string
+	LogChannelID        string
+	LogChannelDefault   bool
+	StaffRoles          []string
+	DisabledCategories  []string
+	Pings               bool
+	MaxOpenTickets      int
 }
 
 type HelpData struct {
@@ -40,6 +129,60 @@ func PopulateTicketData(data TicketData) (string, error) {
 	return buf.String(), nil
 }
 
+func PopulateTicketClosedData(data TicketClosedData) (string, error) {
+	var buf bytes.Buffer
+	t := template.Must(template.New("ticket-closed").Parse(TicketClosedTemplate))
+	if err := t.Execute(&buf, data); err != nil {
+		return "", errors.WithMessage(err, "failed to execute ticket closed template")
+	}
+	return buf.String(), nil
+}
+
+func PopulateTicketReopenedData(data TicketReopenedData) (string, error) {
+	var buf bytes.Buffer
+	t := template.Must(template.New("ticket-reopened").Parse(TicketReopenedTemplate))
+	if err := t.Execute(&buf, data); err != nil {
+		return "", errors.WithMessage(err, "failed to execute ticket reopened template")
+	}
+	return buf.String(), nil
+}
+
+func PopulateTicketInactiveData(data TicketInactiveData) (string, error) {
+	var buf bytes.Buffer
+	t := template.Must(template.New("ticket-inactive").Parse(TicketInactiveTemplate))
+	if err := t.Execute(&buf, data); err != nil {
+		return "", errors.WithMessage(err, "failed to execute ticket inactive template")
+	}
+	return buf.String(), nil
+}
+
+func PopulateTicketEscalatedData(data TicketEscalatedData) (string, error) {
+	var buf bytes.Buffer
+	t := template.Must(template.New("ticket-escalated").Parse(TicketEscalatedTemplate))
+	if err := t.Execute(&buf, data); err != nil {
+		return "", errors.WithMessage(err, "failed to execute ticket escalated template")
+	}
+	return buf.String(), nil
+}
+
+func PopulateTicketSLAData(data TicketSLAData) (string, error) {
+	var buf bytes.Buffer
+	t := template.Must(template.New("ticket-sla").Parse(TicketSLATemplate))
+	if err := t.Execute(&buf, data); err != nil {
+		return "", errors.WithMessage(err, "failed to execute ticket SLA template")
+	}
+	return buf.String(), nil
+}
+
+func PopulateTicketSettingsData(data TicketSettingsData) (string, error) {
+	var buf bytes.Buffer
+	t := template.Must(template.New("ticket-settings").Parse(TicketSettingsTemplate))
+	if err := t.Execute(&buf, data); err != nil {
+		return "", errors.WithMessage(err, "failed to execute ticket settings template")
+	}
+	return buf.String(), nil
+}
+
 func PopulateHelpData(data HelpData) (string, error) {
 	var buf bytes.Buffer
 	t := template.Must(template.New("help").Parse(HelpTemplate))
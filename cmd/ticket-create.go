@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+// CreateTicketID prefixes the custom ID of the ticket creation form, which is followed by the category's title
+// and the number of the ticket being followed up on (0 for none), so that the form survives restarts.
+const CreateTicketID = "/ticket-create"
+
+// maxModalTitleLength is Discord's limit on the length of a modal's title.
+const maxModalTitleLength = 45
+
+var ErrCategoryUnavailable = errors.New("category is not available")
+
+// FollowUpNotFoundError is returned when a new ticket follows up on a ticket which does not exist.
+type FollowUpNotFoundError struct {
+	Number int64
+}
+
+func (e FollowUpNotFoundError) Error() string {
+	return fmt.Sprintf("ticket #%d does not exist", e.Number)
+}
+
+// ValidateNewTicket checks that the member may open a ticket in the category, returning the category.
+func ValidateNewTicket(
+	ctx context.Context, b *Bot, guildID snowflake.ID, openerID snowflake.ID, title string, followUpOf int64,
+) (common.TicketCategory, error) {
+	category, ok := EnabledCategories(b, guildID).Find(title)
+	if !ok {
+		return category, ErrCategoryUnavailable
+	}
+	if err := CheckTicketLimit(ctx, b, guildID, openerID); err != nil {
+		return category, err
+	}
+	if followUpOf != 0 {
+		if _, err := b.Store.GetTicketByNumber(ctx, guildID, followUpOf); errors.Is(err, store.ErrNotFound) {
+			return category, FollowUpNotFoundError{Number: followUpOf}
+		} else if err != nil {
+			return category, errors.WithMessage(err, "failed to get followed up ticket")
+		}
+	}
+	return category, nil
+}
+
+// TicketFormModal builds the ticket creation form for the category: a subject, a description and the
+// category's extra fields.
+func TicketFormModal(b *Bot, category common.TicketCategory, followUpOf int64) discord.ModalCreate {
+	title := []rune("New ticket: " + category.Description)
+	if len(title) > maxModalTitleLength {
+		title = append(title[:maxModalTitleLength-1], '…')
+	}
+	builder := discord.NewModalCreateBuilder().
+		SetCustomID(fmt.Sprintf("%s/%s/%d", CreateTicketID, category.Title, followUpOf)).
+		SetTitle(string(title)).
+		AddActionRow(
+			discord.NewShortTextInput("subject", "Subject").
+				WithRequired(true).
+				WithMinLength(b.Cfg.Tickets.MinSubjectLength).
+				WithMaxLength(b.Cfg.Tickets.MaxSubjectLength).
+				WithPlaceholder("A brief summary of the ticket"),
+		).
+		AddActionRow(
+			discord.NewParagraphTextInput("content", "Description").
+				WithRequired(true).
+				WithMinLength(b.Cfg.Tickets.MinContentLength).
+				WithMaxLength(b.Cfg.Tickets.MaxContentLength).
+				WithPlaceholder("The specifics of the ticket"),
+		)
+	for _, f := range category.Fields {
+		input := discord.NewShortTextInput(f.ID, f.Label)
+		if f.Paragraph {
+			input = discord.NewParagraphTextInput(f.ID, f.Label)
+		}
+		input = input.WithRequired(f.Required).WithMaxLength(f.Limit()).WithPlaceholder(f.Placeholder)
+		if f.MinLength > 0 {
+			input = input.WithMinLength(f.MinLength)
+		}
+		builder.AddActionRow(input)
+	}
+	return builder.Build()
+}
+
+// ParseTicketFormFollowUp parses the followed up ticket number from the ticket creation form's custom ID.
+func ParseTicketFormFollowUp(value string) int64 {
+	followUpOf, _ := strconv.ParseInt(value, 10, 64)
+	return followUpOf
+}
+
+// TicketAnswers collects the answers to the category's extra fields from a submitted ticket creation form,
+// skipping unanswered optional fields.
+func TicketAnswers(category common.TicketCategory, data discord.ModalSubmitInteractionData) store.TicketAnswers {
+	var answers store.TicketAnswers
+	for _, f := range category.Fields {
+		if value, ok := data.OptText(f.ID); ok && value != "" {
+			answers = append(answers, store.TicketAnswer{Label: f.Label, Value: value})
+		}
+	}
+	return answers
+}
+
+// OpenTicket opens a validated, unsaved ticket: it allocates the ticket's number, creates its private thread in
+// the support channel, adds the opener and anyone the access policy names, posts the ticket message and stores it.
+// Should any step after creating the thread fail, the thread is deleted again; storing the ticket fails with a
+// TicketLimitError if the opener has reached the guild's limit of open tickets meanwhile.
+func OpenTicket(ctx context.Context, b *Bot, t *store.Ticket) error {
+	channelID, err := GetSupportChannel(b, &t.GuildID)
+	if err != nil {
+		return err
+	}
+	t.ChannelID = channelID
+	// set ahead of the store, as the ticket message tells members opening tickets outside support hours when to
+	// expect a reply
+	t.CreatedAt = time.Now().UTC()
+	if t.Number, err = b.Store.NextTicketNumber(ctx, t.GuildID); err != nil {
+		return err
+	}
+	if err = createTicketThread(b, t); err == nil {
+		err = storeTicket(ctx, b, t)
+	}
+	if err != nil {
+		discardTicketThread(b, t)
+		return err
+	}
+	RecordTicketEvent(ctx, b, store.TicketEvent{
+		TicketID: t.ID,
+		Kind:     store.TicketEventCreated,
+		ActorID:  t.OpenerID,
+	})
+	slog.Info(
+		"Ticket created",
+		slog.Int64("ticket_id", t.ID),
+		slog.Int64("number", t.Number),
+		slog.Any("thread_id", t.ThreadID),
+	)
+	return nil
+}
+
+// storeTicket stores the new ticket, enforcing the guild's limit of open tickets.
+func storeTicket(ctx context.Context, b *Bot, t *store.Ticket) error {
+	limit := GuildSettings(b, t.GuildID).MaxOpenTickets
+	if err := b.Store.CreateTicket(ctx, t, limit); errors.Is(err, store.ErrTicketLimit) {
+		return TicketLimitError{Limit: limit}
+	} else if err != nil {
+		return errors.WithMessage(err, "failed to store ticket")
+	}
+	return nil
+}
+
+// discardTicketThread deletes the thread of a ticket which could not be opened, if it was created, so that no thread
+// is left behind without a ticket. Failures are logged, as the thread can still be deleted by hand.
+func discardTicketThread(b *Bot, t *store.Ticket) {
+	if t.ThreadID == 0 {
+		return
+	}
+	if err := b.Client.Rest().DeleteChannel(t.ThreadID); err != nil {
+		slog.Error("Failed to delete thread of unopened ticket", slog.Any("thread_id", t.ThreadID), slog.Any("err", err))
+	}
+	t.ThreadID = 0
+}
+
+// createTicketThread creates a private thread for the ticket
+func createTicketThread(b *Bot, t *store.Ticket) error {
+	thread, err := b.Client.Rest().CreateThread(
+		t.ChannelID,
+		discord.GuildPrivateThreadCreate{
+			Name:                TicketThreadName(b, t, ""),
+			AutoArchiveDuration: 60,
+		},
+	)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create thread")
+	}
+	t.ThreadID = thread.ID()
+	if err = b.Client.Rest().AddThreadMember(t.ThreadID, t.OpenerID); err != nil {
+		return errors.WithMessage(err, "failed to add thread member")
+	}
+	AddTicketMembers(b, t)
+	if err = sendTicketContent(b, t); err != nil {
+		return errors.WithMessage(err, "failed to send ticket content")
+	}
+	return nil
+}
+
+// sendTicketContent sends the ticket content, along with its controls, to the ticket's thread
+// and remembers the resulting message so that it can be edited later.
+func sendTicketContent(b *Bot, t *store.Ticket) error {
+	content, err := PopulateTicketContent(b, t)
+	if err != nil {
+		return errors.WithMessage(err, "failed to populate ticket content")
+	}
+	m, err := b.Client.Rest().CreateMessage(
+		t.ThreadID,
+		discord.NewMessageCreateBuilder().
+			SetContent(content).
+			AddContainerComponents(TicketControls(t)...).
+			Build(),
+	)
+	if err != nil {
+		return errors.WithMessage(err, "failed to create message in thread")
+	}
+	t.MessageID = m.ID
+	return nil
+}
+
+// ticketDraftTTL matches how long Discord lets a member fill in a modal.
+const ticketDraftTTL = 15 * time.Minute
+
+// TicketDrafts holds what the ticket creation command collected which does not fit into the form's custom ID,
+// such as the attachment, until the member submits the form. Drafts are kept in memory only; a restart in
+// between merely loses the attachment.
+type TicketDrafts struct {
+	mu     sync.Mutex
+	drafts map[ticketDraftKey]ticketDraft
+}
+
+type ticketDraftKey struct {
+	guildID snowflake.ID
+	userID  snowflake.ID
+}
+
+type ticketDraft struct {
+	attachmentURL string
+	expiresAt     time.Time
+}
+
+func NewTicketDrafts() *TicketDrafts {
+	return &TicketDrafts{drafts: make(map[ticketDraftKey]ticketDraft)}
+}
+
+// Put remembers the member's draft, replacing any earlier one, and forgets expired drafts.
+func (d *TicketDrafts) Put(guildID snowflake.ID, userID snowflake.ID, attachmentURL string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	for k, v := range d.drafts {
+		if now.After(v.expiresAt) {
+			delete(d.drafts, k)
+		}
+	}
+	d.drafts[ticketDraftKey{guildID, userID}] = ticketDraft{attachmentURL: attachmentURL, expiresAt: now.Add(ticketDraftTTL)}
+}
+
+// Take returns and forgets the member's draft attachment, if there is an unexpired one.
+func (d *TicketDrafts) Take(guildID snowflake.ID, userID snowflake.ID) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := ticketDraftKey{guildID, userID}
+	draft, ok := d.drafts[key]
+	delete(d.drafts, key)
+	if !ok || time.Now().After(draft.expiresAt) {
+		return ""
+	}
+	return draft.attachmentURL
+}
+
+// ForgetGuild drops the drafts of every member of the guild.
+func (d *TicketDrafts) ForgetGuild(guildID snowflake.ID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for k := range d.drafts {
+		if k.guildID == guildID {
+			delete(d.drafts, k)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/paginator"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+const (
+	// ticketsPerPage is how many tickets each page of a ticket list shows
+	ticketsPerPage = 10
+	// recentClosedTickets is how many closed tickets a member's own ticket list shows besides the open ones
+	recentClosedTickets = 10
+)
+
+// StaffTicketListReply builds the reply of a deferred interaction listing the tickets matching the filter whose
+// support team the member belongs to, most urgent first. Only the page shown is loaded from the store, as each page
+// is turned to.
+func StaffTicketListReply(
+	ctx context.Context, b *Bot, id string, creatorID snowflake.ID, member discord.Member, filter store.TicketFilter,
+) (discord.MessageUpdate, error) {
+	scope, err := staffScope(b, filter.GuildID, member)
+	if err != nil {
+		return discord.MessageUpdate{}, err
+	}
+	filter.Scope = scope
+	filter.ByPriority = true
+	total, err := b.Store.CountTickets(ctx, filter)
+	if err != nil {
+		return discord.MessageUpdate{}, errors.WithMessage(err, "failed to count tickets")
+	}
+	if total == 0 {
+		return Reply("No tickets match."), nil
+	}
+	return paginatedReply(b, id, creatorID, "Tickets", total, ticketsPerPage, func(page int) []string {
+		// pages are turned long after the interaction which listed them, so each is loaded with its own deadline
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		f := filter
+		f.Limit = ticketsPerPage
+		f.Offset = page * ticketsPerPage
+		tickets, err := b.Store.ListTickets(ctx, f)
+		if err != nil {
+			slog.Error("Failed to list tickets", slog.Any("guild_id", filter.GuildID), slog.Any("err", err))
+			return []string{"This page could not be loaded; please try again."}
+		}
+		now := time.Now().UTC()
+		lines := make([]string, 0, len(tickets))
+		for i := range tickets {
+			lines = append(lines, TicketListLine(b, &tickets[i], now))
+		}
+		return lines
+	}), nil
+}
+
+// staffScope returns the categories of the guild whose support team the member belongs to. Tickets of categories
+// since removed from the configuration are supported by those of moderator-level categories, as GetTicketCategory
+// treats them.
+func staffScope(b *Bot, guildID snowflake.ID, member discord.Member) (*store.CategoryScope, error) {
+	g, err := GetPolicyGuild(b, guildID)
+	if err != nil {
+		return nil, err
+	}
+	p := b.Cfg.GuildPolicy(guildID)
+	scope := &store.CategoryScope{}
+	for _, category := range b.Cfg.GuildCategories(guildID) {
+		scope.Known = append(scope.Known, category.Title)
+		if p.Allows(category.Access, g, member, categoryRoleIDs(b, guildID, category)...) {
+			scope.Allowed = append(scope.Allowed, category.Title)
+		}
+	}
+	removed := common.TicketCategory{Access: common.AccessModerator}
+	scope.AllowUnknown = p.Allows(removed.Access, g, member, categoryRoleIDs(b, guildID, removed)...)
+	return scope, nil
+}
+
+// ListMemberTickets lists the member's open tickets in the guild, most urgent first, followed by their most
+// recently closed ones.
+func ListMemberTickets(
+	ctx context.Context, b *Bot, guildID snowflake.ID, memberID snowflake.ID,
+) ([]store.Ticket, error) {
+	open, err := b.Store.ListTickets(ctx, store.TicketFilter{
+		GuildID:    guildID,
+		OpenerID:   memberID,
+		Status:     store.TicketStatusOpen,
+		ByPriority: true,
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to list open tickets")
+	}
+	closed, err := b.Store.ListTickets(ctx, store.TicketFilter{
+		GuildID:  guildID,
+		OpenerID: memberID,
+		Status:   store.TicketStatusClosed,
+		Limit:    recentClosedTickets,
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to list closed tickets")
+	}
+	return append(open, closed...), nil
+}
+
+// TicketListReply builds the reply of a deferred interaction listing the tickets in pages which only the member
+// who asked for them can turn. The pages are kept by the bot's paginator under the given ID, which should be unique,
+// such as the interaction's.
+func TicketListReply(
+	b *Bot, id string, creatorID snowflake.ID, title string, tickets []store.Ticket, now time.Time,
+) discord.MessageUpdate {
+	if len(tickets) == 0 {
+		return Reply("No tickets match.")
+	}
+	lines := make([]string, 0, len(tickets))
+	for i := range tickets {
+		lines = append(lines, TicketListLine(b, &tickets[i], now))
+	}
+	return paginatedReply(b, id, creatorID, title, len(lines), ticketsPerPage, pageOf(lines, ticketsPerPage))
+}
+
+// paginatedReply builds the reply of a deferred interaction showing total entries in pages of perPage, each page's
+// entries given by entries.
+func paginatedReply(
+	b *Bot, id string, creatorID snowflake.ID, title string, total int, perPage int, entries func(page int) []string,
+) discord.MessageUpdate {
+	var reply discord.MessageUpdate
+	// the paginator can only answer interactions itself, so its answer is captured to be sent as the deferred reply
+	_ = b.Paginator.Update(
+		func(_ discord.InteractionResponseType, data discord.InteractionResponseData, _ ...rest.RequestOpt) error {
+			reply = data.(discord.MessageUpdate)
+			return nil
+		},
+		paginator.Pages{
+			ID:         id,
+			Pages:      (total + perPage - 1) / perPage,
+			Creator:    creatorID,
+			ExpireMode: paginator.ExpireModeAfterLastUsage,
+			PageFunc: func(page int, embed *discord.EmbedBuilder) {
+				embed.SetTitlef("%s (%d)", title, total)
+				embed.SetDescription(strings.Join(entries(page), "\n"))
+			},
+		},
+	)
+	return reply
+}
+
+// pageOf returns the entries of a page of perPage entries, for lists held in memory.
+func pageOf(entries []string, perPage int) func(page int) []string {
+	return func(page int) []string {
+		start := page * perPage
+		return entries[start:min(start+perPage, len(entries))]
+	}
+}
+
+// listSubjectEscaper keeps a subject from breaking out of the link it is shown in
+var listSubjectEscaper = strings.NewReplacer("[", "(", "]", ")")
+
+// TicketListLine describes the ticket in a single line of a ticket list: its SLA flag and priority, a link to its
+// thread, its category and status, and who is handling it.
+func TicketListLine(b *Bot, t *store.Ticket, now time.Time) string {
+	var sb strings.Builder
+	for _, marker := range []string{SLAFlag(TicketSLA(b, t, now)), t.Priority.Marker()} {
+		if marker != "" {
+			sb.WriteString(marker + " ")
+		}
+	}
+	subject := listSubjectEscaper.Replace(t.Subject)
+	fmt.Fprintf(&sb, "**#%d** [%s](%s) · %s", t.Number, subject, TicketURL(t), CategoryLabel(b, t))
+	if t.IsOpen() {
+		fmt.Fprintf(&sb, " · opened <t:%d:R> by <@%s>", t.CreatedAt.Unix(), t.OpenerID)
+	} else if t.ClosedAt != nil {
+		fmt.Fprintf(&sb, " · closed <t:%d:R>", t.ClosedAt.Unix())
+	}
+	if t.AssigneeID != 0 {
+		fmt.Fprintf(&sb, " · handled by <@%s>", t.AssigneeID)
+	}
+	return sb.String()
+}
+
+// TicketURL returns a link which jumps to the ticket message, or to the ticket thread if there is none.
+func TicketURL(t *store.Ticket) string {
+	if t.MessageID == 0 {
+		return fmt.Sprintf("https://discord.com/channels/%s/%s", t.GuildID, t.ThreadID)
+	}
+	return discord.MessageURL(t.GuildID, t.ThreadID, t.MessageID)
+}
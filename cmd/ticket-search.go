@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+const (
+	// maxSearchResults bounds how many tickets a search lists
+	maxSearchResults = 100
+	// searchResultsPerPage is lower than ticketsPerPage to leave room for each result's excerpt
+	searchResultsPerPage = 5
+	// maxSearchSuggestions is the most choices Discord accepts in an autocomplete response
+	maxSearchSuggestions = 25
+	// maxChoiceLength is the longest name or value Discord accepts for an autocomplete choice
+	maxChoiceLength = 100
+)
+
+// SearchTickets searches the guild's tickets whose support team the member belongs to, best matches first.
+func SearchTickets(
+	ctx context.Context, b *Bot, guildID snowflake.ID, member discord.Member, query string,
+) ([]store.TicketSearchResult, error) {
+	scope, err := staffScope(b, guildID, member)
+	if err != nil {
+		return nil, err
+	}
+	results, err := b.Store.SearchTickets(ctx, store.TicketSearchFilter{
+		GuildID: guildID,
+		Query:   query,
+		Scope:   scope,
+		Limit:   maxSearchResults,
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to search tickets")
+	}
+	return results, nil
+}
+
+// TicketSearchReply builds the reply of a deferred interaction listing the search results in pages, each with an
+// excerpt of the text which matched.
+func TicketSearchReply(
+	b *Bot, id string, creatorID snowflake.ID, query string, results []store.TicketSearchResult, now time.Time,
+) discord.MessageUpdate {
+	if len(results) == 0 {
+		return Replyf("No tickets match %q.", query)
+	}
+	entries := make([]string, 0, len(results))
+	for i := range results {
+		entry := TicketListLine(b, &results[i].Ticket, now)
+		if snippet := strings.Join(strings.Fields(results[i].Snippet), " "); snippet != "" {
+			entry += "\n> " + snippet
+		}
+		entries = append(entries, entry)
+	}
+	return paginatedReply(
+		b, id, creatorID, fmt.Sprintf("Tickets matching %q", query),
+		len(entries), searchResultsPerPage, pageOf(entries, searchResultsPerPage),
+	)
+}
+
+// SearchSuggestions suggests tickets to search for as the member types: the most recent tickets matching what has
+// been typed so far, those whose subject contains it first. Each suggestion searches for the ticket's subject, to
+// find others like it.
+func SearchSuggestions(
+	ctx context.Context, b *Bot, guildID snowflake.ID, member discord.Member, input string,
+) ([]discord.AutocompleteChoiceString, error) {
+	scope, err := staffScope(b, guildID, member)
+	if err != nil {
+		return nil, err
+	}
+	var tickets []store.Ticket
+	if strings.TrimSpace(input) == "" {
+		tickets, err = b.Store.ListTickets(ctx, store.TicketFilter{
+			GuildID: guildID,
+			Scope:   scope,
+			Limit:   maxSearchSuggestions,
+		})
+	} else {
+		var results []store.TicketSearchResult
+		results, err = b.Store.SearchTickets(ctx, store.TicketSearchFilter{
+			GuildID: guildID,
+			Query:   input,
+			Scope:   scope,
+			Recent:  true,
+			Limit:   maxSearchSuggestions,
+		})
+		for _, r := range results {
+			tickets = append(tickets, r.Ticket)
+		}
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to find suggestions")
+	}
+	choices := make([]discord.AutocompleteChoiceString, 0, len(tickets))
+	for _, t := range tickets {
+		choices = append(choices, discord.AutocompleteChoiceString{
+			Name:  truncateChoice(fmt.Sprintf("#%d %s", t.Number, t.Subject)),
+			Value: truncateChoice(t.Subject),
+		})
+	}
+	matches := common.GetFilteredAutocompleteOptions(input, choices)
+	for _, c := range choices {
+		if !slices.ContainsFunc(matches, func(m discord.AutocompleteChoiceString) bool { return m.Name == c.Name }) {
+			matches = append(matches, c)
+		}
+	}
+	return matches, nil
+}
+
+func truncateChoice(s string) string {
+	if runes := []rune(s); len(runes) > maxChoiceLength {
+		return string(runes[:maxChoiceLength])
+	}
+	return s
+}
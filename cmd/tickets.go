@@ -0,0 +1,461 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/json"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/commands"
+	"github.com/kapparina/ticketsplease/cmd/common"
+	"github.com/kapparina/ticketsplease/cmd/store"
+	"github.com/kapparina/ticketsplease/cmd/templates"
+)
+
+// DefaultCloseReason is recorded when a ticket is closed without a reason.
+const DefaultCloseReason = "No reason provided"
+
+// Component custom IDs. Tickets are resolved from the thread the interaction happens in,
+// so the IDs stay valid across restarts.
+const (
+	CloseTicketID    = "/ticket-close"
+	ReopenTicketID   = "/ticket-reopen"
+	ClaimTicketID    = "/ticket-claim"
+	UnclaimTicketID  = "/ticket-unclaim"
+	PriorityTicketID = "/ticket-priority"
+)
+
+// maxThreadNameLength is the longest name Discord accepts for a thread
+const maxThreadNameLength = 100
+
+var (
+	ErrNotTicketThread = errors.New("channel is not a ticket thread")
+	ErrTicketNotFound  = errors.New("ticket does not exist")
+	ErrTicketNotOpen   = errors.New("ticket is not open")
+	ErrTicketOpen      = errors.New("ticket is already open")
+	ErrNotPermitted    = errors.New("member may not manage ticket")
+	ErrNotStaff        = errors.New("member is not part of the ticket's support team")
+	ErrTicketUnclaimed = errors.New("ticket is not assigned")
+)
+
+// ReopenWindowError is returned when a ticket was closed too long ago to be reopened.
+type ReopenWindowError struct {
+	Number int64
+	Window time.Duration
+}
+
+func (e ReopenWindowError) Error() string {
+	return fmt.Sprintf("ticket #%d was closed more than %s ago", e.Number, e.Window)
+}
+
+// TicketControls returns the action rows attached to the ticket content, reflecting whether the ticket is claimed
+// and its priority.
+func TicketControls(t *store.Ticket) []discord.ContainerComponent {
+	claim := discord.NewPrimaryButton("Claim", ClaimTicketID)
+	if t.AssigneeID != 0 {
+		claim = discord.NewSecondaryButton("Unclaim", UnclaimTicketID)
+	}
+	return []discord.ContainerComponent{
+		discord.NewActionRow(CloseTicketButton(), claim),
+		discord.NewActionRow(PriorityMenu(t)),
+	}
+}
+
+// PriorityMenu returns the menu posted alongside the ticket content which lets the support team set the ticket's
+// priority, with the current priority selected.
+func PriorityMenu(t *store.Ticket) discord.StringSelectMenuComponent {
+	options := make([]discord.StringSelectMenuOption, 0, len(common.Priorities))
+	for _, p := range common.Priorities {
+		option := discord.NewStringSelectMenuOption(p.Label()+" priority", string(p)).
+			WithDefault(p == t.Priority || t.Priority == "" && p == common.PriorityNormal)
+		if marker := p.Marker(); marker != "" {
+			option = option.WithEmoji(discord.ComponentEmoji{Name: marker})
+		}
+		options = append(options, option)
+	}
+	return discord.NewStringSelectMenu(PriorityTicketID, "Priority", options...)
+}
+
+// CloseTicketButton returns the button posted alongside the ticket content which starts the close flow.
+func CloseTicketButton() discord.ButtonComponent {
+	return discord.NewDangerButton("Close", CloseTicketID)
+}
+
+// ReopenTicketButton returns the button posted alongside the closure summary which reopens the ticket.
+func ReopenTicketButton() discord.ButtonComponent {
+	return discord.NewSecondaryButton("Reopen", ReopenTicketID)
+}
+
+// TicketErrorMessage maps the ticket sentinel errors to a message suitable for replying to the member.
+// It reports false for any other error, which should be handled as an internal failure.
+func TicketErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, ErrNotTicketThread):
+		return "This can only be used inside a ticket thread.", true
+	case errors.Is(err, ErrTicketNotFound):
+		return "That ticket does not exist.", true
+	case errors.Is(err, ErrTicketNotOpen):
+		return "This ticket is already closed.", true
+	case errors.Is(err, ErrTicketOpen):
+		return "This ticket is already open.", true
+	case errors.Is(err, ErrNotPermitted):
+		return "Only the ticket's creator or its support team can do that.", true
+	case errors.Is(err, ErrNotStaff):
+		return "Only the ticket's support team can do that.", true
+	case errors.Is(err, ErrTicketUnclaimed):
+		return "This ticket is not assigned to anyone.", true
+	case errors.Is(err, ErrInactivityPaused):
+		return "Inactivity reminders are already paused for this ticket.", true
+	case errors.Is(err, ErrInactivityNotPaused):
+		return "Inactivity reminders are not paused for this ticket.", true
+	case errors.Is(err, ErrPriorityInvalid):
+		return "That is not a ticket priority.", true
+	case errors.Is(err, ErrPriorityUnchanged):
+		return "This ticket already has that priority.", true
+	case errors.Is(err, store.ErrConflict):
+		return "Someone else changed this ticket at the same time; please try again.", true
+	case errors.Is(err, ErrCategoryUnavailable):
+		return "That is not a ticket category here; please pick one of the suggested categories.", true
+	}
+	var followUpErr FollowUpNotFoundError
+	if errors.As(err, &followUpErr) {
+		return fmt.Sprintf("Ticket #%d does not exist, so it cannot be followed up.", followUpErr.Number), true
+	}
+	var conflictErr ClaimConflictError
+	if errors.As(err, &conflictErr) {
+		return fmt.Sprintf(
+			"Ticket #%d is already assigned to <@%s>. Use `/%s %s` to reassign it.",
+			conflictErr.Number, conflictErr.AssigneeID, commands.Ticket.Name, commands.TicketAssign,
+		), true
+	}
+	var limitErr TicketLimitError
+	if errors.As(err, &limitErr) {
+		return fmt.Sprintf(
+			"You already have %d open tickets, which is the most allowed here. Please wait for one to be closed.",
+			limitErr.Limit,
+		), true
+	}
+	var windowErr ReopenWindowError
+	if errors.As(err, &windowErr) {
+		if windowErr.Window <= 0 {
+			return fmt.Sprintf(
+				"Closed tickets cannot be reopened here. Please open a follow-up ticket with `/%s` and set `follow_up` to %d.",
+				commands.TicketCreateCommandName, windowErr.Number,
+			), true
+		}
+		return fmt.Sprintf(
+			"Ticket #%d was closed more than %d days ago and can no longer be reopened. "+
+				"Please open a follow-up ticket with `/%s` and set `follow_up` to %d.",
+			windowErr.Number, int(windowErr.Window.Hours()/24), commands.TicketCreateCommandName, windowErr.Number,
+		), true
+	}
+	return "", false
+}
+
+// GetManageableTicket looks up the ticket hosted in threadID and checks that the member may manage it.
+func GetManageableTicket(ctx context.Context, b *Bot, threadID snowflake.ID, member *discord.ResolvedMember) (*store.Ticket, error) {
+	t, err := b.Store.GetTicketByThread(ctx, threadID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotTicketThread
+	} else if err != nil {
+		return nil, errors.WithMessage(err, "failed to get ticket")
+	}
+	return authorizeTicket(b, t, member, true)
+}
+
+// GetStaffTicket looks up the ticket hosted in threadID and checks that the member is part of its support team.
+func GetStaffTicket(ctx context.Context, b *Bot, threadID snowflake.ID, member *discord.ResolvedMember) (*store.Ticket, error) {
+	t, err := b.Store.GetTicketByThread(ctx, threadID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotTicketThread
+	} else if err != nil {
+		return nil, errors.WithMessage(err, "failed to get ticket")
+	}
+	return authorizeTicket(b, t, member, false)
+}
+
+// GetManageableTicketByNumber looks up the guild's ticket with the given number and checks that the member may manage it.
+func GetManageableTicketByNumber(
+	ctx context.Context, b *Bot, guildID snowflake.ID, number int64, member *discord.ResolvedMember,
+) (*store.Ticket, error) {
+	t, err := b.Store.GetTicketByNumber(ctx, guildID, number)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrTicketNotFound
+	} else if err != nil {
+		return nil, errors.WithMessage(err, "failed to get ticket")
+	}
+	return authorizeTicket(b, t, member, true)
+}
+
+// authorizeTicket checks that the member is part of the ticket's support team or, if allowOpener is set, its opener.
+func authorizeTicket(b *Bot, t *store.Ticket, member *discord.ResolvedMember, allowOpener bool) (*store.Ticket, error) {
+	if allowOpener {
+		ok, err := CanManageTicket(b, t, member)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrNotPermitted
+		}
+		return t, nil
+	}
+	if member == nil {
+		return nil, ErrNotStaff
+	}
+	ok, err := IsTicketStaff(b, t, member.Member)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotStaff
+	}
+	return t, nil
+}
+
+// GetTicketCategory returns the configured category of the ticket. Tickets whose category has since been removed
+// from the configuration are treated as a moderator-level category of the same name.
+func GetTicketCategory(b *Bot, t *store.Ticket) common.TicketCategory {
+	if category, ok := b.Cfg.GuildCategory(t.GuildID, t.Category); ok {
+		return category
+	}
+	return common.TicketCategory{Title: t.Category, Description: t.Category, Access: common.AccessModerator}
+}
+
+// CategoryLabel returns the human-readable description of the ticket's category.
+func CategoryLabel(b *Bot, t *store.Ticket) string {
+	return GetTicketCategory(b, t).Description
+}
+
+// TicketThreadName formats the name of a ticket thread, trimmed to Discord's channel name limit.
+// The marker of the ticket's priority is put in front, and the assignee's name is appended when given.
+func TicketThreadName(b *Bot, t *store.Ticket, assigneeName string) string {
+	name := fmt.Sprintf("#%d %s - %s | (%s)", t.Number, t.OpenerName, t.Subject, CategoryLabel(b, t))
+	if marker := t.Priority.Marker(); marker != "" {
+		name = marker + " " + name
+	}
+	if assigneeName != "" {
+		name = fmt.Sprintf("%s [%s]", name, assigneeName)
+	}
+	runes := []rune(name)
+	if len(runes) > maxThreadNameLength {
+		runes = runes[:maxThreadNameLength]
+	}
+	return string(runes)
+}
+
+// PopulateTicketContent generates the ticket content message for a stored ticket.
+// It resolves the roles to ping from the ticket's category and incorporates them into the ticket template.
+func PopulateTicketContent(b *Bot, t *store.Ticket) (string, error) {
+	category := GetTicketCategory(b, t)
+	roleIDs, userIDs, err := GetTicketMentions(b, t)
+	if err != nil {
+		return "", err
+	}
+	var assignee string
+	if t.AssigneeID != 0 {
+		assignee = t.AssigneeID.String()
+	}
+	answers := make([]templates.TicketAnswer, 0, len(t.Answers))
+	for _, a := range t.Answers {
+		answers = append(answers, templates.TicketAnswer{Label: a.Label, Value: a.Value})
+	}
+	// counted from the ticket's creation, so that the notice stays put when the content is edited later
+	var repliesFrom int64
+	if next, ok := b.Cfg.BusinessHours(t.GuildID).NextOpen(t.CreatedAt); ok && next.After(t.CreatedAt) {
+		repliesFrom = next.Unix()
+	}
+	return templates.PopulateTicketData(templates.TicketData{
+		Number:        t.Number,
+		Category:      category.Description,
+		Username:      t.OpenerName,
+		Subject:       t.Subject,
+		Content:       t.Content,
+		Answers:       answers,
+		Moderators:    roleIDs,
+		Members:       userIDs,
+		AttachmentURL: t.AttachmentURL,
+		FollowUpOf:    t.FollowUpOf,
+		Assignee:      assignee,
+		RepliesFrom:   repliesFrom,
+	})
+}
+
+// RecordTicketEvent appends an entry to the ticket's history. Failures are logged rather than returned so that
+// a history hiccup never undoes an action which has already been applied in Discord.
+func RecordTicketEvent(ctx context.Context, b *Bot, e store.TicketEvent) {
+	if err := b.Store.AddTicketEvent(ctx, &e); err != nil {
+		slog.Error(
+			"Failed to record ticket event",
+			slog.Int64("ticket_id", e.TicketID),
+			slog.String("kind", string(e.Kind)),
+			slog.Any("err", err),
+		)
+	}
+}
+
+// CloseTicket marks the ticket as closed, then posts a closure summary to its thread and locks and archives the
+// thread. Marking the ticket first settles concurrent closures before anything is posted; should the thread fail to
+// be closed, the summary is deleted and the ticket marked as open again so that the two stay in step.
+// closerID is 0 when the bot closes the ticket itself.
+func CloseTicket(ctx context.Context, b *Bot, t *store.Ticket, closerID snowflake.ID, reason string) error {
+	if !t.IsOpen() {
+		return ErrTicketNotOpen
+	}
+	if reason == "" {
+		reason = DefaultCloseReason
+	}
+	now := time.Now().UTC()
+	var closer string
+	if closerID != 0 {
+		closer = closerID.String()
+	}
+	content, err := templates.PopulateTicketClosedData(templates.TicketClosedData{
+		Number:   t.Number,
+		ClosedBy: closer,
+		Reason:   reason,
+		OpenedAt: t.CreatedAt.Unix(),
+		ClosedAt: now.Unix(),
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed to populate closure summary")
+	}
+	if err = b.Store.CloseTicket(ctx, t.ID, now, closerID, reason); err != nil {
+		// someone else closed the ticket meanwhile and closes its thread
+		if errors.Is(err, store.ErrConflict) {
+			return ErrTicketNotOpen
+		}
+		return errors.WithMessage(err, "failed to mark ticket as closed")
+	}
+	summary, err := b.Client.Rest().CreateMessage(
+		t.ThreadID,
+		discord.NewMessageCreateBuilder().
+			SetContent(content).
+			SetAllowedMentions(&discord.AllowedMentions{}).
+			AddActionRow(ReopenTicketButton()).
+			Build(),
+	)
+	if err != nil {
+		undoCloseTicket(ctx, b, t, now)
+		return errors.WithMessage(err, "failed to send closure summary")
+	}
+	if _, err = b.Client.Rest().UpdateChannel(
+		t.ThreadID,
+		discord.GuildThreadUpdate{
+			Locked:   json.Ptr(true),
+			Archived: json.Ptr(true),
+		},
+	); err != nil {
+		if derr := b.Client.Rest().DeleteMessage(t.ThreadID, summary.ID); derr != nil {
+			slog.Error("Failed to delete closure summary", slog.Int64("ticket_id", t.ID), slog.Any("err", derr))
+		}
+		undoCloseTicket(ctx, b, t, now)
+		return errors.WithMessage(err, "failed to lock and archive ticket thread")
+	}
+	t.Status = store.TicketStatusClosed
+	t.ClosedAt = &now
+	t.ClosedBy = closerID
+	t.CloseReason = reason
+	RecordTicketEvent(ctx, b, store.TicketEvent{
+		TicketID: t.ID,
+		Kind:     store.TicketEventClosed,
+		ActorID:  closerID,
+		Detail:   reason,
+	})
+	// a resolution deadline missed since the last scheduled check is still recorded as breached
+	if err = RecordSLA(ctx, b, t, now, false); err != nil {
+		slog.Error("Failed to record ticket SLA", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+	}
+	publishTranscriptAsync(b, *t)
+	slog.Info(
+		"Ticket closed",
+		slog.Int64("ticket_id", t.ID),
+		slog.Int64("number", t.Number),
+		slog.Any("closed_by", closerID),
+	)
+	return nil
+}
+
+// undoCloseTicket marks a ticket whose thread could not be closed as open again.
+func undoCloseTicket(ctx context.Context, b *Bot, t *store.Ticket, closedAt time.Time) {
+	if err := b.Store.UndoCloseTicket(ctx, t.ID, closedAt); err != nil {
+		slog.Error("Failed to mark ticket as open again", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+	}
+}
+
+// ReopenTicket unarchives and unlocks a closed ticket's thread, re-adds its opener and pings the category's
+// roles again. Tickets closed longer ago than the guild's reopen window are refused with a ReopenWindowError.
+func ReopenTicket(ctx context.Context, b *Bot, t *store.Ticket, reopenerID snowflake.ID) error {
+	if t.IsOpen() {
+		return ErrTicketOpen
+	}
+	window := b.Cfg.Tickets.ReopenWindow(t.GuildID)
+	if window <= 0 || (t.ClosedAt != nil && time.Since(*t.ClosedAt) > window) {
+		return ReopenWindowError{Number: t.Number, Window: window}
+	}
+	if _, err := b.Client.Rest().UpdateChannel(
+		t.ThreadID,
+		discord.GuildThreadUpdate{
+			Archived: json.Ptr(false),
+			Locked:   json.Ptr(false),
+		},
+	); err != nil {
+		return errors.WithMessage(err, "failed to unarchive ticket thread")
+	}
+	if err := b.Client.Rest().AddThreadMember(t.ThreadID, t.OpenerID); err != nil {
+		return errors.WithMessage(err, "failed to re-add ticket opener")
+	}
+	AddTicketMembers(b, t)
+	if err := b.Store.ReopenTicket(ctx, t.ID); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return ErrTicketOpen
+		}
+		return errors.WithMessage(err, "failed to mark ticket as reopened")
+	}
+	t.Status = store.TicketStatusOpen
+	t.ClosedAt = nil
+	t.ClosedBy = 0
+	t.CloseReason = ""
+	t.ReopenCount++
+	restartInactivity(ctx, b, t)
+	RecordTicketEvent(ctx, b, store.TicketEvent{
+		TicketID: t.ID,
+		Kind:     store.TicketEventReopened,
+		ActorID:  reopenerID,
+	})
+	roleIDs, userIDs, err := GetTicketMentions(b, t)
+	if err != nil {
+		return err
+	}
+	content, err := templates.PopulateTicketReopenedData(templates.TicketReopenedData{
+		Number:      t.Number,
+		ReopenedBy:  reopenerID.String(),
+		ReopenCount: t.ReopenCount,
+		Moderators:  roleIDs,
+		Members:     userIDs,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed to populate reopen message")
+	}
+	if _, err = b.Client.Rest().CreateMessage(
+		t.ThreadID,
+		discord.NewMessageCreateBuilder().
+			SetContent(content).
+			AddActionRow(CloseTicketButton()).
+			Build(),
+	); err != nil {
+		return errors.WithMessage(err, "failed to send reopen message")
+	}
+	slog.Info(
+		"Ticket reopened",
+		slog.Int64("ticket_id", t.ID),
+		slog.Int64("number", t.Number),
+		slog.Any("reopened_by", reopenerID),
+		slog.Int("reopen_count", t.ReopenCount),
+	)
+	return nil
+}
@@ -0,0 +1,411 @@
+package transcript
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/go-pdf/fpdf"
+	"github.com/pkg/errors"
+)
+
+// Limits on the images embedded in PDF transcripts, so that a transcript still fits into a Discord upload and
+// decoding an image cannot exhaust the bot's memory. Images beyond them are listed by name and link like any other
+// attachment.
+const (
+	maxImageSize      = 4 << 20
+	maxImagesSize     = 8 << 20
+	maxImagePixels    = 16 << 20
+	imageFetchTimeout = 20 * time.Second
+)
+
+// Page layout of PDF transcripts, in millimetres.
+const (
+	pdfMargin         = 15
+	pdfLineHeight     = 5
+	pdfMaxImageWidth  = 120
+	pdfMaxImageHeight = 90
+	pdfLabelWidth     = 35
+	// pdfImageDPI is the resolution images are scaled down to at their largest size
+	pdfImageDPI = 150
+)
+
+// pdfFont is the name PDF transcripts register their font under.
+const pdfFont = "transcript"
+
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	dejaVuRegular []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	dejaVuBold []byte
+	//go:embed fonts/DejaVuSansCondensed-Oblique.ttf
+	dejaVuItalic []byte
+)
+
+// Font is a TrueType font PDF transcripts are set in, in each of the styles they use.
+type Font struct {
+	Regular, Bold, Italic []byte
+}
+
+// DefaultFont is DejaVu Sans Condensed, which covers the Latin, Greek and Cyrillic scripts and many symbols but
+// not Chinese, Japanese or Korean.
+var DefaultFont = Font{Regular: dejaVuRegular, Bold: dejaVuBold, Italic: dejaVuItalic}
+
+// LoadFont reads a TrueType (.ttf) font from a file for PDF transcripts, such as one covering a script DefaultFont
+// lacks. The font is used for every style.
+func LoadFont(path string) (font Font, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Font{}, errors.WithMessage(err, "failed to read font")
+	}
+	// fpdf panics on some malformed fonts
+	defer func() {
+		if r := recover(); r != nil {
+			font, err = Font{}, errors.Errorf("failed to load font %s: %v", path, r)
+		}
+	}()
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(pdfFont, "", data)
+	// fpdf only reports a font it could not parse as undefined once it is used
+	pdf.SetFont(pdfFont, "", 10)
+	if err = pdf.Error(); err != nil {
+		return Font{}, errors.WithMessagef(err, "failed to load font %s", path)
+	}
+	return Font{Regular: data, Bold: data, Italic: data}, nil
+}
+
+// LoadImages downloads the images attached to the transcript's messages, so that PDF transcripts can show them
+// inline. Images which cannot be fetched or decoded, or which would exceed the size limits, are skipped.
+func LoadImages(ctx context.Context, tr *Transcript, client *http.Client) {
+	var total int
+	for i := range tr.Messages {
+		for j := range tr.Messages[i].Attachments {
+			a := &tr.Messages[i].Attachments[j]
+			if !a.IsImage() || a.Size > maxImageSize || total+a.Size > maxImagesSize {
+				continue
+			}
+			data, err := fetchImage(ctx, client, a.URL)
+			if err != nil {
+				slog.Warn("Failed to fetch transcript image", slog.String("url", a.URL), slog.Any("err", err))
+				continue
+			}
+			a.Image = data
+			total += len(data)
+		}
+	}
+}
+
+// fetchImage downloads the image and re-encodes it as a JPEG on a white background, which every PDF reader can
+// show, whatever the original's format, transparency or colour depth. Images with more pixels than can safely be
+// decoded are refused, and larger images are scaled down to the resolution at which they are shown.
+func fetchImage(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, imageFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to build request")
+	}
+	rs, err := client.Do(req)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to download image")
+	}
+	defer rs.Body.Close()
+	if rs.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %s", rs.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(rs.Body, maxImageSize))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to download image")
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to decode image")
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, errors.Errorf("image of %dx%d pixels is too large", cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to decode image")
+	}
+	flat := flattenImage(img, pdfMaxImageWidth*pdfImageDPI*10/254, pdfMaxImageHeight*pdfImageDPI*10/254)
+	var buf bytes.Buffer
+	if err = jpeg.Encode(&buf, flat, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, errors.WithMessage(err, "failed to encode image")
+	}
+	return buf.Bytes(), nil
+}
+
+// flattenImage draws the image on a white background, scaled down to fit within the given number of pixels. Each
+// pixel of a scaled down image averages the pixels it covers, so that text in screenshots stays legible.
+func flattenImage(img image.Image, maxWidth int, maxHeight int) *image.RGBA {
+	src := img.Bounds()
+	scale := min(1, float64(maxWidth)/float64(src.Dx()), float64(maxHeight)/float64(src.Dy()))
+	width, height := max(1, int(float64(src.Dx())*scale)), max(1, int(float64(src.Dy())*scale))
+	flat := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0, y1 := src.Min.Y+y*src.Dy()/height, src.Min.Y+(y+1)*src.Dy()/height
+		for x := range width {
+			x0, x1 := src.Min.X+x*src.Dx()/width, src.Min.X+(x+1)*src.Dx()/width
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					// the colour is premultiplied by its alpha, so adding the missing alpha lays it over white
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, n = r+uint64(cr+0xffff-ca), g+uint64(cg+0xffff-ca), b+uint64(cb+0xffff-ca), n+1
+				}
+			}
+			flat.SetRGBA(x, y, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(b / n >> 8), A: 0xff})
+		}
+	}
+	return flat
+}
+
+// renderPDF lays the transcript out as a PDF in the given font: a header describing the ticket, the message log
+// with any loaded images inline, and a closing summary. The document may be printed and copied from but not
+// modified. Characters beyond the Basic Multilingual Plane, such as most emoji, cannot be set and are replaced.
+func renderPDF(tr Transcript, font Font) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(pdfFont, "", font.Regular)
+	pdf.AddUTF8FontFromBytes(pdfFont, "B", font.Bold)
+	pdf.AddUTF8FontFromBytes(pdfFont, "I", font.Italic)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle(fmt.Sprintf("Ticket #%d transcript", tr.Ticket.Number), true)
+	pdf.SetSubject(tr.Ticket.Subject, true)
+	pdf.SetCreator("TicketsPlease", true)
+	pdf.SetCreationDate(tr.GeneratedAt)
+	pdf.SetProtection(fpdf.CnProtectPrint|fpdf.CnProtectCopy, "", "")
+	pdf.AliasNbPages("")
+	w := &pdfWriter{pdf: pdf}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin + 5)
+		pdf.SetFont(pdfFont, "I", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, w.tr(fmt.Sprintf(
+			"Ticket #%d, generated %s - page %d of {nb}", tr.Ticket.Number, formatTime(tr.GeneratedAt), pdf.PageNo(),
+		)), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+	w.header(tr)
+	w.messages(tr)
+	w.summary(tr)
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, errors.WithMessage(err, "failed to render pdf transcript")
+	}
+	return buf.Bytes(), nil
+}
+
+type pdfWriter struct {
+	pdf *fpdf.Fpdf
+}
+
+// tr replaces the characters beyond the Basic Multilingual Plane, which fpdf refuses to set, with the replacement
+// character.
+func (w *pdfWriter) tr(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r > 0xFFFF {
+			return utf8.RuneError
+		}
+		return r
+	}, s)
+}
+
+func (w *pdfWriter) header(tr Transcript) {
+	t := tr.Ticket
+	w.pdf.SetFont(pdfFont, "B", 16)
+	w.pdf.MultiCell(0, 8, w.tr(fmt.Sprintf("Ticket #%d: %s", t.Number, t.Subject)), "", "L", false)
+	w.pdf.Ln(2)
+	w.field("Category", t.Category)
+	w.field("Opened by", userLabel(tr, t.OpenerID, t.OpenerName))
+	if t.AssigneeID != 0 {
+		w.field("Assignee", userLabel(tr, t.AssigneeID, ""))
+	} else {
+		w.field("Assignee", "none")
+	}
+	w.field("Status", t.Status)
+	w.field("Opened", formatTime(t.CreatedAt))
+	if t.ClosedAt != nil {
+		w.field("Closed", formatTime(*t.ClosedAt))
+	}
+	w.field("Thread", t.ThreadID.String())
+	if t.Content != "" {
+		w.pdf.Ln(2)
+		w.pdf.SetFont(pdfFont, "", 10)
+		w.pdf.MultiCell(0, pdfLineHeight, w.tr(t.Content), "", "L", false)
+	}
+	w.rule()
+}
+
+func (w *pdfWriter) messages(tr Transcript) {
+	w.heading("Messages")
+	if len(tr.Messages) == 0 {
+		w.pdf.SetFont(pdfFont, "I", 10)
+		w.pdf.CellFormat(0, pdfLineHeight, "No messages were posted.", "", 1, "L", false, 0, "")
+	}
+	for _, m := range tr.Messages {
+		author := m.AuthorName
+		if m.AuthorBot {
+			author += " (bot)"
+		}
+		stamp := formatTime(m.CreatedAt)
+		if m.EditedAt != nil {
+			stamp += ", edited " + formatTime(*m.EditedAt)
+		}
+		w.pdf.SetFont(pdfFont, "B", 10)
+		w.pdf.SetTextColor(0, 0, 0)
+		w.pdf.CellFormat(w.pdf.GetStringWidth(w.tr(author))+2, pdfLineHeight, w.tr(author), "", 0, "L", false, 0, "")
+		w.pdf.SetFont(pdfFont, "", 8)
+		w.pdf.SetTextColor(120, 120, 120)
+		w.pdf.CellFormat(0, pdfLineHeight, w.tr(stamp), "", 1, "L", false, 0, "")
+		w.pdf.SetTextColor(0, 0, 0)
+		w.pdf.SetFont(pdfFont, "", 10)
+		if m.Content != "" {
+			w.pdf.MultiCell(0, pdfLineHeight, w.tr(m.Content), "", "L", false)
+		}
+		for _, e := range m.Embeds {
+			w.embed(e)
+		}
+		for _, a := range m.Attachments {
+			w.attachment(m, a)
+		}
+		w.pdf.Ln(3)
+	}
+}
+
+func (w *pdfWriter) embed(e Embed) {
+	left, _, _, _ := w.pdf.GetMargins()
+	w.pdf.SetLeftMargin(left + 5)
+	w.pdf.SetX(left + 5)
+	defer func() {
+		w.pdf.SetLeftMargin(left)
+		w.pdf.SetX(left)
+	}()
+	if e.Title != "" {
+		w.pdf.SetFont(pdfFont, "B", 9)
+		w.pdf.MultiCell(0, pdfLineHeight, w.tr(e.Title), "", "L", false)
+	}
+	w.pdf.SetFont(pdfFont, "", 9)
+	if e.Description != "" {
+		w.pdf.MultiCell(0, pdfLineHeight, w.tr(e.Description), "", "L", false)
+	}
+	for _, f := range e.Fields {
+		w.pdf.MultiCell(0, pdfLineHeight, w.tr(f.Name+": "+f.Value), "", "L", false)
+	}
+	w.pdf.SetFont(pdfFont, "", 10)
+}
+
+// attachment shows a loaded image inline and lists every attachment by name and link.
+func (w *pdfWriter) attachment(m Message, a Attachment) {
+	if len(a.Image) > 0 {
+		name := fmt.Sprintf("%s/%s", m.ID, a.Filename)
+		opts := fpdf.ImageOptions{ImageType: "JPG"}
+		info := w.pdf.RegisterImageOptionsReader(name, opts, bytes.NewReader(a.Image))
+		if info != nil {
+			width, height := fitImage(info.Width(), info.Height())
+			_, pageHeight := w.pdf.GetPageSize()
+			if w.pdf.GetY()+height > pageHeight-pdfMargin {
+				w.pdf.AddPage()
+			}
+			w.pdf.ImageOptions(name, w.pdf.GetX(), w.pdf.GetY(), width, height, true, opts, 0, a.URL)
+		}
+	}
+	w.pdf.SetFont(pdfFont, "", 9)
+	w.pdf.SetTextColor(0, 0, 200)
+	w.pdf.WriteLinkString(pdfLineHeight, w.tr(fmt.Sprintf("Attachment: %s (%d bytes)", a.Filename, a.Size)), a.URL)
+	w.pdf.Ln(pdfLineHeight)
+	w.pdf.SetTextColor(0, 0, 0)
+	w.pdf.SetFont(pdfFont, "", 10)
+}
+
+func (w *pdfWriter) summary(tr Transcript) {
+	t := tr.Ticket
+	w.rule()
+	w.heading("Summary")
+	var (
+		participants []string
+		attachments  int
+	)
+	for _, m := range tr.Messages {
+		attachments += len(m.Attachments)
+		if !m.AuthorBot && !slices.Contains(participants, m.AuthorName) {
+			participants = append(participants, m.AuthorName)
+		}
+	}
+	w.field("Status", t.Status)
+	if t.ClosedAt != nil {
+		closedBy := "automatically"
+		if t.ClosedBy != 0 {
+			closedBy = userLabel(tr, t.ClosedBy, "")
+		}
+		w.field("Closed by", closedBy)
+		w.field("Open for", t.ClosedAt.Sub(t.CreatedAt).Round(time.Minute).String())
+		if t.CloseReason != "" {
+			w.field("Reason", t.CloseReason)
+		}
+	}
+	w.field("Messages", fmt.Sprint(len(tr.Messages)))
+	w.field("Attachments", fmt.Sprint(attachments))
+	if len(participants) > 0 {
+		w.field("Participants", strings.Join(participants, ", "))
+	}
+}
+
+func (w *pdfWriter) heading(text string) {
+	w.pdf.SetFont(pdfFont, "B", 13)
+	w.pdf.CellFormat(0, 8, w.tr(text), "", 1, "L", false, 0, "")
+	w.pdf.Ln(1)
+}
+
+func (w *pdfWriter) field(label string, value string) {
+	w.pdf.SetFont(pdfFont, "B", 10)
+	w.pdf.CellFormat(pdfLabelWidth, pdfLineHeight, w.tr(label), "", 0, "L", false, 0, "")
+	w.pdf.SetFont(pdfFont, "", 10)
+	w.pdf.MultiCell(0, pdfLineHeight, w.tr(value), "", "L", false)
+}
+
+func (w *pdfWriter) rule() {
+	w.pdf.Ln(3)
+	left, _, right, _ := w.pdf.GetMargins()
+	pageWidth, _ := w.pdf.GetPageSize()
+	w.pdf.SetDrawColor(180, 180, 180)
+	w.pdf.Line(left, w.pdf.GetY(), pageWidth-right, w.pdf.GetY())
+	w.pdf.Ln(4)
+}
+
+// fitImage scales an image down to fit the largest size images are shown at, keeping its aspect ratio.
+func fitImage(width float64, height float64) (float64, float64) {
+	scale := min(1, pdfMaxImageWidth/width, pdfMaxImageHeight/height)
+	return width * scale, height * scale
+}
+
+// userLabel names the user by the name they posted under in the ticket, falling back to the given name and to
+// their ID.
+func userLabel(tr Transcript, id snowflake.ID, name string) string {
+	for _, m := range tr.Messages {
+		if m.AuthorID == id {
+			name = m.AuthorName
+			break
+		}
+	}
+	if name == "" {
+		return id.String()
+	}
+	return fmt.Sprintf("%s (%s)", name, id)
+}
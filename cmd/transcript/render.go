@@ -0,0 +1,91 @@
+package transcript
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//go:embed transcript.gomd
+var MarkdownTemplate string
+
+//go:embed transcript.gohtml
+var HTMLTemplate string
+
+type Format string
+
+const (
+	FormatHTML     Format = "html"
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+	FormatPDF      Format = "pdf"
+)
+
+// Formats lists every supported transcript format.
+var Formats = []Format{FormatHTML, FormatMarkdown, FormatJSON, FormatPDF}
+
+// Extension returns the file extension used for transcripts rendered in the format.
+func (f Format) Extension() string {
+	switch f {
+	case FormatMarkdown:
+		return "md"
+	default:
+		return string(f)
+	}
+}
+
+// ParseFormat returns the Format with the given name.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(string(f), name) {
+			return f, nil
+		}
+	}
+	return "", errors.Errorf("unknown transcript format %q", name)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05 MST")
+}
+
+var templateFuncs = map[string]any{
+	"timestamp": formatTime,
+	"quote": func(s string) string {
+		return "> " + strings.ReplaceAll(s, "\n", "\n> ")
+	},
+}
+
+// Render renders the transcript in the given format. PDF transcripts are set in the given font.
+func Render(tr Transcript, f Format, font Font) ([]byte, error) {
+	var buf bytes.Buffer
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(tr); err != nil {
+			return nil, errors.WithMessage(err, "failed to encode transcript")
+		}
+	case FormatMarkdown:
+		t := texttemplate.Must(texttemplate.New("transcript").Funcs(templateFuncs).Parse(MarkdownTemplate))
+		if err := t.Execute(&buf, tr); err != nil {
+			return nil, errors.WithMessage(err, "failed to execute markdown transcript template")
+		}
+	case FormatHTML:
+		t := htmltemplate.Must(htmltemplate.New("transcript").Funcs(templateFuncs).Parse(HTMLTemplate))
+		if err := t.Execute(&buf, tr); err != nil {
+			return nil, errors.WithMessage(err, "failed to execute html transcript template")
+		}
+	case FormatPDF:
+		return renderPDF(tr, font)
+	default:
+		return nil, errors.Errorf("unknown transcript format %q", f)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,158 @@
+package transcript
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/store"
+)
+
+// pageSize is the largest number of messages Discord returns per request
+const pageSize = 100
+
+// Transcript is a point-in-time record of a ticket and every message posted in its thread.
+type Transcript struct {
+	Ticket      Ticket    `json:"ticket"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Messages    []Message `json:"messages"`
+}
+
+type Ticket struct {
+	Number      int64        `json:"number"`
+	GuildID     snowflake.ID `json:"guild_id"`
+	ThreadID    snowflake.ID `json:"thread_id"`
+	Category    string       `json:"category"`
+	Subject     string       `json:"subject"`
+	Content     string       `json:"content"`
+	OpenerID    snowflake.ID `json:"opener_id"`
+	OpenerName  string       `json:"opener_name"`
+	AssigneeID  snowflake.ID `json:"assignee_id,omitempty"`
+	Status      string       `json:"status"`
+	CreatedAt   time.Time    `json:"created_at"`
+	ClosedAt    *time.Time   `json:"closed_at,omitempty"`
+	ClosedBy    snowflake.ID `json:"closed_by,omitempty"`
+	CloseReason string       `json:"close_reason,omitempty"`
+}
+
+type Message struct {
+	ID          snowflake.ID `json:"id"`
+	AuthorID    snowflake.ID `json:"author_id"`
+	AuthorName  string       `json:"author_name"`
+	AuthorBot   bool         `json:"author_bot"`
+	Content     string       `json:"content"`
+	CreatedAt   time.Time    `json:"created_at"`
+	EditedAt    *time.Time   `json:"edited_at,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	Embeds      []Embed      `json:"embeds,omitempty"`
+}
+
+type Attachment struct {
+	Filename    string `json:"filename"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
+	// Image is the image as shown in PDF transcripts, if LoadImages loaded it
+	Image []byte `json:"-"`
+}
+
+// IsImage reports whether the attachment can be displayed inline.
+func (a Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+type Embed struct {
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Fields      []EmbedField `json:"fields,omitempty"`
+}
+
+type EmbedField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// FetchMessages retrieves every message in the channel, oldest first, paging past Discord's per-request limit.
+func FetchMessages(ctx context.Context, client rest.Rest, channelID snowflake.ID) ([]discord.Message, error) {
+	var (
+		messages []discord.Message
+		before   snowflake.ID
+	)
+	for {
+		page, err := client.GetMessages(channelID, 0, before, 0, pageSize, rest.WithCtx(ctx))
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to get messages")
+		}
+		messages = append(messages, page...)
+		if len(page) < pageSize {
+			break
+		}
+		// Pages are returned newest first, so the last message is the oldest seen so far.
+		before = page[len(page)-1].ID
+	}
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+// New builds a transcript of the ticket from its thread's messages, which are expected oldest first.
+func New(t *store.Ticket, messages []discord.Message) Transcript {
+	tr := Transcript{
+		Ticket: Ticket{
+			Number:      t.Number,
+			GuildID:     t.GuildID,
+			ThreadID:    t.ThreadID,
+			Category:    t.Category,
+			Subject:     t.Subject,
+			Content:     t.Content,
+			OpenerID:    t.OpenerID,
+			OpenerName:  t.OpenerName,
+			AssigneeID:  t.AssigneeID,
+			Status:      string(t.Status),
+			CreatedAt:   t.CreatedAt,
+			ClosedAt:    t.ClosedAt,
+			ClosedBy:    t.ClosedBy,
+			CloseReason: t.CloseReason,
+		},
+		GeneratedAt: time.Now().UTC(),
+		Messages:    make([]Message, 0, len(messages)),
+	}
+	for _, m := range messages {
+		msg := Message{
+			ID:         m.ID,
+			AuthorID:   m.Author.ID,
+			AuthorName: m.Author.EffectiveName(),
+			AuthorBot:  m.Author.Bot,
+			Content:    m.Content,
+			CreatedAt:  m.CreatedAt,
+			EditedAt:   m.EditedTimestamp,
+		}
+		for _, a := range m.Attachments {
+			var contentType string
+			if a.ContentType != nil {
+				contentType = *a.ContentType
+			}
+			msg.Attachments = append(msg.Attachments, Attachment{
+				Filename:    a.Filename,
+				URL:         a.URL,
+				ContentType: contentType,
+				Size:        a.Size,
+			})
+		}
+		for _, e := range m.Embeds {
+			embed := Embed{Title: e.Title, Description: e.Description, URL: e.URL}
+			for _, f := range e.Fields {
+				embed.Fields = append(embed.Fields, EmbedField{Name: f.Name, Value: f.Value})
+			}
+			msg.Embeds = append(msg.Embeds, embed)
+		}
+		tr.Messages = append(tr.Messages, msg)
+	}
+	return tr
+}
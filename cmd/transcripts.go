@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/pkg/errors"
+
+	"github.com/kapparina/ticketsplease/cmd/store"
+	"github.com/kapparina/ticketsplease/cmd/transcript"
+)
+
+// renderedTranscript is a transcript rendered in a single format, ready to be stored or uploaded.
+type renderedTranscript struct {
+	Filename string
+	Data     []byte
+}
+
+// PublishTranscript renders the ticket's thread in every format configured for its guild, stores the results in the
+// transcript directory, posts them to the guild's log channel and, if enabled, sends them to the ticket's opener.
+// The thread's messages also replace the ticket's searchable messages, so that edits and messages posted before the
+// bot was tracking the thread can be found. Each destination is attempted independently; the first failure is
+// returned once all have been tried.
+func PublishTranscript(ctx context.Context, b *Bot, t *store.Ticket) error {
+	files, tr, err := renderTranscript(ctx, b, t, b.Cfg.TranscriptFormats(t.GuildID))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	if err = indexTranscript(ctx, b, t, tr); err != nil {
+		errs = append(errs, err)
+	}
+	if err = storeTranscript(b, t, files); err != nil {
+		errs = append(errs, err)
+	}
+	if channelID := LogChannel(b, t.GuildID); channelID != 0 {
+		content := fmt.Sprintf(
+			"Transcript for ticket #%d (%s) opened by <@%s>, closed %s: %s",
+			t.Number, CategoryLabel(b, t), t.OpenerID, closedBy(t), t.CloseReason,
+		)
+		if _, err = b.Client.Rest().CreateMessage(channelID, transcriptMessage(content, files), rest.WithCtx(ctx)); err != nil {
+			errs = append(errs, errors.WithMessage(err, "failed to post transcript to log channel"))
+		}
+	}
+	if b.Cfg.ShouldDMTranscript(t.GuildID) {
+		if err = dmTranscript(ctx, b, t, files); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	slog.Info("Transcript published", slog.Int64("ticket_id", t.ID), slog.Int("messages", len(tr.Messages)))
+	return nil
+}
+
+// TranscriptReply renders the ticket's thread in the given format on demand, as the reply of a deferred interaction
+// with the transcript attached. The ticket may still be open.
+func TranscriptReply(ctx context.Context, b *Bot, t *store.Ticket, format transcript.Format) (discord.MessageUpdate, error) {
+	files, _, err := renderTranscript(ctx, b, t, []transcript.Format{format})
+	if err != nil {
+		return discord.MessageUpdate{}, err
+	}
+	builder := discord.NewMessageUpdateBuilder().SetContentf("Transcript of ticket #%d as of now:", t.Number)
+	for _, f := range files {
+		builder.AddFile(f.Filename, "", bytes.NewReader(f.Data))
+	}
+	return builder.Build(), nil
+}
+
+// renderTranscript fetches the ticket's thread and renders it in each of the given formats. Attached images are
+// only downloaded when rendering a PDF, the one format which embeds them.
+func renderTranscript(
+	ctx context.Context, b *Bot, t *store.Ticket, formats []transcript.Format,
+) ([]renderedTranscript, transcript.Transcript, error) {
+	messages, err := transcript.FetchMessages(ctx, b.Client.Rest(), t.ThreadID)
+	if err != nil {
+		return nil, transcript.Transcript{}, errors.WithMessage(err, "failed to fetch ticket messages")
+	}
+	tr := transcript.New(t, messages)
+	if slices.Contains(formats, transcript.FormatPDF) {
+		transcript.LoadImages(ctx, &tr, http.DefaultClient)
+	}
+	files := make([]renderedTranscript, 0, len(formats))
+	for _, format := range formats {
+		data, err := transcript.Render(tr, format, b.Cfg.Transcripts.Font())
+		if err != nil {
+			return nil, tr, err
+		}
+		files = append(files, renderedTranscript{
+			Filename: fmt.Sprintf("ticket-%d.%s", t.Number, format.Extension()),
+			Data:     data,
+		})
+	}
+	return files, tr, nil
+}
+
+// indexTranscript replaces the ticket's searchable messages with the transcript's messages from members.
+func indexTranscript(ctx context.Context, b *Bot, t *store.Ticket, tr transcript.Transcript) error {
+	contents := make([]string, 0, len(tr.Messages))
+	for _, m := range tr.Messages {
+		if !m.AuthorBot && m.Content != "" {
+			contents = append(contents, m.Content)
+		}
+	}
+	return b.Store.IndexTicketMessages(ctx, t.ID, contents)
+}
+
+// publishTranscriptAsync publishes the ticket's transcript in the background so that closing stays responsive.
+func publishTranscriptAsync(b *Bot, t store.Ticket) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		if err := PublishTranscript(ctx, b, &t); err != nil {
+			slog.Error("Failed to publish transcript", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+		}
+	}()
+}
+
+// storeTranscript writes the rendered transcripts to <directory>/<guild id>/, if a directory is configured.
+func storeTranscript(b *Bot, t *store.Ticket, files []renderedTranscript) error {
+	if b.Cfg.Transcripts.Directory == "" {
+		return nil
+	}
+	dir := filepath.Join(b.Cfg.Transcripts.Directory, t.GuildID.String())
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return errors.WithMessage(err, "failed to create transcript directory")
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.Filename), f.Data, 0o640); err != nil {
+			return errors.WithMessagef(err, "failed to write transcript %q", f.Filename)
+		}
+	}
+	return nil
+}
+
+// dmTranscript sends the rendered transcripts to the ticket's opener. Members with closed DMs are skipped quietly.
+func dmTranscript(ctx context.Context, b *Bot, t *store.Ticket, files []renderedTranscript) error {
+	dm, err := b.Client.Rest().CreateDMChannel(t.OpenerID, rest.WithCtx(ctx))
+	if err != nil {
+		return errors.WithMessage(err, "failed to open DM channel with ticket opener")
+	}
+	content := fmt.Sprintf("Your ticket #%d (%s) has been closed. A transcript is attached.", t.Number, t.Subject)
+	if _, err = b.Client.Rest().CreateMessage(dm.ID(), transcriptMessage(content, files), rest.WithCtx(ctx)); err != nil {
+		slog.Warn("Failed to DM transcript to ticket opener", slog.Int64("ticket_id", t.ID), slog.Any("err", err))
+	}
+	return nil
+}
+
+func transcriptMessage(content string, files []renderedTranscript) discord.MessageCreate {
+	builder := discord.NewMessageCreateBuilder().
+		SetContent(content).
+		SetAllowedMentions(&discord.AllowedMentions{})
+	for _, f := range files {
+		builder.AddFile(f.Filename, "", bytes.NewReader(f.Data))
+	}
+	return builder.Build()
+}
+
+// closedBy describes who closed the ticket, for messages about it.
+func closedBy(t *store.Ticket) string {
+	if t.ClosedBy == 0 {
+		return "automatically"
+	}
+	return fmt.Sprintf("by <@%s>", t.ClosedBy)
+}
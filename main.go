@@ -28,6 +28,7 @@ var (
 func main() {
 	shouldSyncCommands := flag.Bool("sync-commands", true, "Whether to sync commands to discord")
 	path := flag.String("config", "config.toml", "path to config")
+	dryRun := flag.Bool("dry-run", false, "Print the changes the bot would make to each guild and its commands, then exit")
 	flag.Parse()
 	cfg, err := cmd.LoadConfig(*path)
 	if err != nil {
@@ -43,15 +44,73 @@ func main() {
 	)
 	slog.Info("Command sync status", slog.Bool("sync", *shouldSyncCommands))
 	b := cmd.New(*cfg, Version, Commit, GitTag)
+	storeCtx, storeCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	err = b.SetupStore(storeCtx, *dryRun)
+	storeCancel()
+	if err != nil {
+		slog.Error("Failed to setup ticket store", slog.Any("err", err))
+		os.Exit(-1)
+	}
+	defer func() {
+		if err := b.Store.Close(); err != nil {
+			slog.Error("Failed to close ticket store", slog.Any("err", err))
+		}
+	}()
 	m := handler.New()
-	m.Use(middleware.Logger)
+	m.Use(middleware.Logger, cmd.ErrorMiddleware(b))
 	m.Command("/test", handlers.TestHandler)
 	m.Autocomplete("/test", handlers.TestAutocompleteHandler)
 	m.Command("/version", handlers.VersionHandler(b))
 	m.Component("/test-button", components.TestComponent)
-	m.Command("/ticket", handlers.CreateTicketHandler(b))
+	m.Route("/ticket", func(r handler.Router) {
+		r.Command("/"+commands.TicketCreate, handlers.CreateTicketHandler(b))
+		r.Autocomplete("/"+commands.TicketCreate, handlers.TicketCategoryAutocompleteHandler(b))
+		r.Command("/"+commands.TicketClose, handlers.CloseTicketHandler(b))
+		r.Command("/"+commands.TicketReopen, handlers.ReopenTicketHandler(b))
+		r.Command("/"+commands.TicketAssign, handlers.AssignTicketHandler(b))
+		r.Command("/"+commands.TicketUnassign, handlers.UnassignTicketHandler(b))
+		r.Command("/"+commands.TicketTranscript, handlers.TranscriptTicketHandler(b))
+		r.Command("/"+commands.TicketPause, handlers.PauseTicketHandler(b))
+		r.Command("/"+commands.TicketResume, handlers.ResumeTicketHandler(b))
+		r.Command("/"+commands.TicketSLA, handlers.SLATicketHandler(b))
+		r.Command("/"+commands.TicketPriority, handlers.PriorityTicketHandler(b))
+	})
+	m.Route("/"+commands.Tickets.Name, func(r handler.Router) {
+		r.Command("/"+commands.TicketsList, handlers.ListTicketsHandler(b))
+		r.Autocomplete("/"+commands.TicketsList, handlers.TicketCategoryAutocompleteHandler(b))
+		r.Command("/"+commands.TicketsMine, handlers.MyTicketsHandler(b))
+		r.Command("/"+commands.TicketsSearch, handlers.SearchTicketsHandler(b))
+		r.Autocomplete("/"+commands.TicketsSearch, handlers.TicketSearchAutocompleteHandler(b))
+	})
+	m.Route("/"+commands.TicketSettings.Name, func(r handler.Router) {
+		r.Command("/"+commands.SettingsShow, handlers.ShowSettingsHandler(b))
+		r.Command("/"+commands.SettingsSupportChannel, handlers.SupportChannelSettingsHandler(b))
+		r.Command("/"+commands.SettingsLogChannel, handlers.LogChannelSettingsHandler(b))
+		r.Route("/"+commands.SettingsStaffRoles, func(r handler.Router) {
+			r.Command("/"+commands.SettingsStaffRoleAdd, handlers.AddStaffRoleHandler(b))
+			r.Command("/"+commands.SettingsStaffRoleDrop, handlers.RemoveStaffRoleHandler(b))
+		})
+		r.Command("/"+commands.SettingsCategory, handlers.CategorySettingsHandler(b))
+		r.Autocomplete("/"+commands.SettingsCategory, handlers.CategorySettingsAutocompleteHandler(b))
+		r.Command("/"+commands.SettingsPings, handlers.PingSettingsHandler(b))
+		r.Command("/"+commands.SettingsLimits, handlers.LimitSettingsHandler(b))
+		r.Command("/"+commands.SettingsReset, handlers.ResetSettingsHandler(b))
+		r.Command("/"+commands.SettingsPreview, handlers.PreviewSettingsHandler(b))
+	})
+	m.Modal(cmd.CreateTicketID+"/{category}/{follow_up}", components.CreateTicketModal(b))
+	m.Component(cmd.PanelCategoryID, components.PanelCategoryComponent(b))
+	m.Component(cmd.PanelHelpID, components.PanelHelpComponent(b))
+	m.Component(cmd.CloseTicketID, components.CloseTicketComponent(b))
+	m.Modal(cmd.CloseTicketID, components.CloseTicketModal(b))
+	m.Component(cmd.ReopenTicketID, components.ReopenTicketComponent(b))
+	m.Component(cmd.ClaimTicketID, components.ClaimTicketComponent(b))
+	m.Component(cmd.UnclaimTicketID, components.UnclaimTicketComponent(b))
+	m.Component(cmd.PriorityTicketID, components.PriorityTicketComponent(b))
 	m.Command("/help", handlers.HelpHandler(b))
-	if err = b.SetupBot(m, bot.NewListenerFunc(b.OnReady), bot.NewListenerFunc(b.OnJoin), handlers.MessageHandler(b)); err != nil {
+	if err = b.SetupBot(
+		m, bot.NewListenerFunc(b.OnReady), bot.NewListenerFunc(b.OnJoin), b.GuildChangeListener(),
+		handlers.MessageHandler(b),
+	); err != nil {
 		slog.Error("Failed to setup bot", slog.Any("err", err))
 		os.Exit(-1)
 	}
@@ -60,6 +119,13 @@ func main() {
 		defer cancel()
 		b.Client.Close(ctx)
 	}()
+	if *dryRun {
+		if err = cmd.DryRun(b, os.Stdout); err != nil {
+			slog.Error("Failed to plan changes", slog.Any("err", err))
+			os.Exit(-1)
+		}
+		return
+	}
 	if *shouldSyncCommands {
 		slog.Info(
 			"Attempting to sync commands...",
@@ -80,6 +146,9 @@ func main() {
 		slog.Error("Failed to open gateway", slog.Any("err", err))
 		os.Exit(-1)
 	}
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	go cmd.RunScheduler(schedulerCtx, b)
 	slog.Info("Bot is running. Press CTRL-C to exit.")
 	s := make(chan os.Signal, 1)
 	signal.Notify(s, syscall.SIGINT, syscall.SIGTERM)